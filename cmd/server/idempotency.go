@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// idempotencyPurgeInterval is how often expired idempotency keys are deleted.
+// Expired keys are also freed on reuse, so this only bounds the table size.
+const idempotencyPurgeInterval = time.Hour
+
+// purgeIdempotencyKeys deletes expired idempotency keys every interval until ctx is cancelled.
+func purgeIdempotencyKeys(ctx context.Context, repo models.IdempotencyRepository, interval time.Duration, appLogger *logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := repo.DeleteExpired(ctx)
+			if err != nil {
+				appLogger.Warn("Failed to purge expired idempotency keys", zap.Error(err))
+				continue
+			}
+			if n > 0 {
+				appLogger.Info("Purged expired idempotency keys", zap.Int64("count", n))
+			}
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/internal/mail"
+	"example.com/auth_service/pkg/logger"
+)
+
+// newMailer builds the email sender selected by cfg.Email.Sender.
+func newMailer(cfg *config.Config, appLogger *logger.Logger) (mail.Sender, error) {
+	switch cfg.Email.Sender {
+	case "smtp":
+		return mail.NewSMTPSender(cfg.Email)
+	default:
+		appLogger.Warn("Using the log email sender; emails are not delivered")
+		return mail.NewLogSender(appLogger), nil
+	}
+}
@@ -1,25 +1,75 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
-	"net/http" // Required for http.StatusOK if used in protected route example
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"example.com/auth_service/internal/apierror"
 	"example.com/auth_service/internal/auth"
 	"example.com/auth_service/internal/config"
 	"example.com/auth_service/internal/database"
+	"example.com/auth_service/internal/erasure"
 	"example.com/auth_service/internal/handlers"
+	"example.com/auth_service/internal/health"
+	"example.com/auth_service/internal/metrics"
 	"example.com/auth_service/internal/middleware"
-	"example.com/auth_service/internal/s3service" // Add S3 service import
+	"example.com/auth_service/internal/migrations"
+	"example.com/auth_service/internal/ratelimit"
+	"example.com/auth_service/internal/reconcile"
+	"example.com/auth_service/internal/retention"
+	"example.com/auth_service/internal/routes"
+	"example.com/auth_service/internal/s3service"
+	"example.com/auth_service/internal/secrets"
+	"example.com/auth_service/internal/tracing"
 	"example.com/auth_service/pkg/logger"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.uber.org/zap" // For logger error handling
 )
 
+// poolStatsInterval is how often database pool statistics are logged.
+const poolStatsInterval = time.Minute
+
 func main() {
-	// Load configuration (from .env and OS)
-	cfg, err := config.Load()
+	// Registered first so it runs after every other deferred cleanup
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	// Subcommands share the binary with the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "reconcile":
+			runReconcile(os.Args[2:])
+			return
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		case "config":
+			runConfig(os.Args[2:])
+			return
+		}
+	}
+
+	// Load configuration: flags override env vars, which override the config file
+	var cfgOpts config.Options
+	cfgOpts.RegisterFlags(flag.CommandLine)
+	migrateOnStartup := flag.Bool("migrate", false, "apply pending database migrations before serving (same as -set DB_MIGRATE_ON_STARTUP=true)")
+	flag.Parse()
+	if *migrateOnStartup {
+		cfgOpts.Set("DB_MIGRATE_ON_STARTUP", "true")
+	}
+	cfg, err := config.LoadWith(cfgOpts)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
@@ -35,74 +85,225 @@ func main() {
 			log.Printf("Warning: failed to sync logger: %v\n", syncErr)
 		}
 	}()
-	appLogger.Info("Logger initialized", zap.String("level", cfg.LogLevel), zap.String("format", cfg.LogFormat))
+	appLogger.Info("Logger initialized", zap.String("level", cfg.LogLevel), zap.String("format", cfg.LogFormat), zap.String("env", cfg.Env))
+	for _, problem := range cfg.InsecureSettings() {
+		appLogger.Warn("Insecure configuration; production would refuse to start", zap.String("problem", problem))
+	}
+
+	// Initialize tracing; spans are flushed on shutdown, before the logger is synced
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			appLogger.Warn("Failed to flush traces", zap.Error(err))
+		}
+	}()
 
 	// Initialize database connection
-	db, err := database.Connect(cfg.Database)
+	db, err := database.ConnectCluster(context.Background(), cfg.Database, appLogger)
 	if err != nil {
 		appLogger.Fatal("Failed to connect to database", zap.Error(err))
 	}
 	defer db.Close()
-	appLogger.Info("Database connection successful")
 
-	// Initialize S3 Service
-	s3Svc, err := s3service.NewS3Service(cfg.S3, appLogger)
+	if cfg.Database.MigrateOnStartup {
+		migrator, err := migrations.NewMigrator(db.Primary, appLogger)
+		if err != nil {
+			appLogger.Fatal("Failed to load migrations", zap.Error(err))
+		}
+		n, err := migrator.Up(context.Background())
+		if err != nil {
+			appLogger.Fatal("Failed to apply migrations", zap.Error(err))
+		}
+		appLogger.Info("Database migrations up to date", zap.Int("applied", n))
+	}
+
+	// Initialize blob storage (S3/MinIO, local directory or memory)
+	blobStore, err := newBlobStore(cfg, appLogger)
 	if err != nil {
-		appLogger.Fatal("Failed to initialize S3 service", zap.Error(err))
+		appLogger.Fatal("Failed to initialize blob store", zap.Error(err))
+	}
+	s3Store, _ := blobStore.(*s3service.S3Service) // nil for the local and memory backends
+	blobStore = metrics.InstrumentBlobStore(blobStore, cfg.Storage.Backend)
+	metrics.RegisterDBStats(db.Primary.DB, "primary")
+	for addr, pool := range db.ReplicaPools() {
+		metrics.RegisterDBStats(pool.DB, "replica:"+addr)
 	}
 
 	// Initialize Gin router
 	// gin.SetMode(gin.ReleaseMode) // Uncomment for production
-	router := gin.Default()
-	// router.Use(gin.Recovery()) // gin.Default() already includes Recovery and Logger middleware
-
-	// Setup CORS middleware
-	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowOrigins = []string{"http://localhost:3000"} // URL вашего фронтенда
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
-	// Если вы планируете использовать cookies или аутентификацию через заголовки, которые должны быть доступны JS
-	// corsConfig.AllowCredentials = true
-	router.Use(cors.New(corsConfig)) // Применение middleware
+	apierror.UseJSONFieldNames() // Validation errors name fields as they appear in the request body
+	router := gin.New()          // Not gin.Default(): requests are logged by middleware.AccessLog
+	router.HandleMethodNotAllowed = true
+	// ClientIP (access logs, rate limits) only believes X-Forwarded-For from these
+	if err := router.SetTrustedProxies(cfg.Security.TrustedProxies); err != nil {
+		appLogger.Fatal("Invalid trusted proxies", zap.Error(err))
+	}
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName)) // Server span for every request, named by route template
+	router.Use(middleware.RequestID())                      // Inside the span, so the request ID is attached to it
+	router.Use(middleware.AccessLog(appLogger))
+	router.Use(gin.Recovery())
+	router.Use(metrics.Middleware())           // Early, so requests aborted by later middleware are counted too
+	router.Use(apierror.Middleware(appLogger)) // Renders errors returned by handlers and middleware below
+	router.Use(middleware.SecurityHeaders(cfg.Security))
+	router.Use(middleware.CORS(cfg.CORS))
+	router.Use(middleware.ReadYourWrites(cfg.Database.ReadYourWritesWindow))
 
 	// Setup dependencies
 	userRepo := database.NewUserRepository(db, appLogger)
 	audioRepo := database.NewAudioRepository(db, appLogger)
+	usageRepo := database.NewUsageRepository(db, appLogger)
+	idempotencyRepo := database.NewIdempotencyRepository(db, appLogger)
+	erasureRepo := database.NewErasureRepository(db, appLogger)
+	auditRepo := database.NewAuditRepository(db, appLogger)
+	txManager := database.NewTxManager(db)
 
 	// Pass userRepo to AuthService
 	authSvc := auth.NewAuthService(cfg.JWT.SecretKey, cfg.JWT.ExpirationHours, userRepo)
 
-	userHandler := handlers.NewUserHandler(authSvc, userRepo, appLogger)
-	audioHandler := handlers.NewAudioHandler(s3Svc, audioRepo, appLogger)
+	// Background jobs are stopped and drained on shutdown
+	bgCtx, cancelBg := context.WithCancel(context.Background())
+	defer cancelBg()
+	var jobs backgroundJobs
 
-	// Setup routes
-	apiV1 := router.Group("/api/v1")
-	{
-		userRoutes := apiV1.Group("/users")
-		{
-			userRoutes.POST("/register", userHandler.RegisterUser)
-			userRoutes.POST("/login", userHandler.LoginUser)
+	// Secrets mounted as files (DB_PASSWORD_FILE etc.) are re-read when rotated
+	secretWatcher := secrets.NewWatcher(cfg.Secrets.PollInterval, appLogger)
+	if path, ok := cfg.Secrets.Files["DB_PASSWORD"]; ok {
+		secretWatcher.Watch("DB_PASSWORD", path, cfg.Database.Password, db.SetPassword)
+	}
+	if path, ok := cfg.Secrets.Files["JWT_SECRET_KEY"]; ok {
+		secretWatcher.Watch("JWT_SECRET_KEY", path, cfg.JWT.SecretKey, func(key string) error {
+			authSvc.RotateSecret(key)
+			return nil
+		})
+	}
+	if s3Store != nil {
+		if path, ok := cfg.Secrets.Files["S3_ACCESS_KEY_ID"]; ok {
+			secretWatcher.Watch("S3_ACCESS_KEY_ID", path, cfg.S3.AccessKeyID, func(id string) error {
+				s3Store.SetCredentials(id, "")
+				return nil
+			})
 		}
+		if path, ok := cfg.Secrets.Files["S3_SECRET_ACCESS_KEY"]; ok {
+			secretWatcher.Watch("S3_SECRET_ACCESS_KEY", path, cfg.S3.SecretAccessKey, func(secret string) error {
+				s3Store.SetCredentials("", secret)
+				return nil
+			})
+		}
+	}
+	jobs.Go(func() { secretWatcher.Run(bgCtx) })
+
+	jobs.Go(func() { database.MonitorPool(bgCtx, db.Primary, poolStatsInterval, appLogger) })
+	jobs.Go(func() { db.MonitorReplicas(bgCtx, cfg.Database.ReplicaCheckInterval) })
+	jobs.Go(func() { purgeIdempotencyKeys(bgCtx, idempotencyRepo, idempotencyPurgeInterval, appLogger) })
+
+	if cfg.Reconcile.Interval > 0 {
+		reconciler := reconcile.NewReconciler(blobStore, audioRepo, usageRepo, txManager, appLogger)
+		jobs.Go(func() {
+			reconciler.RunPeriodically(bgCtx, cfg.Reconcile.Interval, reconcile.Options{
+				DryRun: cfg.Reconcile.DryRun,
+				MinAge: cfg.Reconcile.MinAge,
+			})
+		})
+	}
+
+	eraser := erasure.NewEraser(blobStore, audioRepo, erasureRepo, appLogger)
+	jobs.Go(func() { eraser.RunPeriodically(bgCtx, cfg.Erasure.Interval) })
+
+	if cfg.Retention.SweepInterval > 0 {
+		sweeper := retention.NewSweeper(blobStore, audioRepo, usageRepo, txManager, cfg.Retention, appLogger)
+		jobs.Go(func() { sweeper.RunPeriodically(bgCtx) })
+	}
 
-		// Audio routes (protected)
-		authMW := middleware.AuthMiddleware(authSvc, appLogger)
-		audioRoutes := apiV1.Group("/audio")
-		audioRoutes.Use(authMW) // Apply auth middleware to all /audio routes
-		{
-			audioRoutes.POST("/upload", audioHandler.UploadAudioFile)
+	// Rate limiter backing every rate-limited route; redis shares the buckets across instances
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+
+	// Readiness checks for /readyz
+	checker := health.NewChecker(cfg.Health.CacheTTL)
+	checker.Register("database", cfg.Health.CheckTimeout, func(ctx context.Context) (any, error) {
+		details := gin.H{"pool": database.Stats(db.Primary)}
+		if replicas := db.ReplicaHealth(); len(replicas) > 0 {
+			details["replicas_healthy"] = replicas // Reads fall back to the primary, so replicas don't gate readiness
 		}
+		return details, db.Primary.PingContext(ctx)
+	})
+	checker.Register("storage", cfg.Health.CheckTimeout, func(ctx context.Context) (any, error) {
+		return gin.H{"backend": cfg.Storage.Backend}, blobStore.Ping(ctx)
+	})
+	if cfg.RateLimit.Backend == "redis" {
+		redisLimiter, err := ratelimit.NewRedisLimiter(context.Background(), cfg.RateLimit.RedisURL)
+		if err != nil {
+			appLogger.Fatal("Failed to initialize rate limiter", zap.Error(err))
+		}
+		defer redisLimiter.Close()
+		limiter = redisLimiter
+		checker.Register("rate_limiter", cfg.Health.CheckTimeout, func(ctx context.Context) (any, error) {
+			// Requests are let through while Redis is down, so it is reported but doesn't gate readiness
+			details := gin.H{"backend": "redis", "redis_healthy": true}
+			if err := redisLimiter.Ping(ctx); err != nil {
+				details["redis_healthy"] = false
+				details["redis_error"] = err.Error()
+			}
+			return details, nil
+		})
+	}
+	healthHandler := handlers.NewHealthHandler(checker, appLogger)
 
-		// Example of a protected route (requires JWT)
-		// protectedRoutes := apiV1.Group("/protected")
+	mailer, err := newMailer(cfg, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize email sender", zap.Error(err))
 	}
+	userHandler := handlers.NewUserHandler(authSvc, userRepo, usageRepo, cfg.Quota, cfg.Email, mailer, appLogger)
+	accountHandler := handlers.NewAccountHandler(userRepo, usageRepo, audioRepo, erasureRepo, blobStore, appLogger)
+	audioHandler := handlers.NewAudioHandler(blobStore, audioRepo, usageRepo, txManager, cfg.Quota, appLogger)
+	adminHandler := handlers.NewAdminHandler(userRepo, usageRepo, audioRepo, auditRepo, blobStore, cfg.Quota, appLogger)
 
-	router.GET("/ping", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"message": "pong"})
+	routes.Register(router, routes.Deps{
+		Config:          cfg,
+		AuthService:     authSvc,
+		Limiter:         limiter,
+		IdempotencyRepo: idempotencyRepo,
+		AuditRepo:       auditRepo,
+		Logger:          appLogger,
+		Health:          healthHandler,
+		User:            userHandler,
+		Account:         accountHandler,
+		Audio:           audioHandler,
+		Admin:           adminHandler,
 	})
 
 	// Start server
+	srv := &http.Server{
+		Addr:              ":" + cfg.AppPort,
+		Handler:           router,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.ListenAndServe()
+	}()
 	appLogger.Info("Server starting", zap.String("port", cfg.AppPort))
-	if err := router.Run(":" + cfg.AppPort); err != nil {
-		appLogger.Fatal("Failed to start server", zap.Error(err))
+
+	sigCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	select {
+	case err := <-serverErr:
+		// ListenAndServe only returns before Shutdown on failure, e.g. the port is taken
+		appLogger.Error("Failed to start server", zap.Error(err))
+		exitCode = 1
+	case <-sigCtx.Done():
+		stopSignals() // A second signal kills the process immediately
 	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancelShutdown()
+	shutdown(shutdownCtx, srv, cancelBg, &jobs, appLogger)
+	// Deferred calls close the database and flush the logger
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/internal/database"
+	"example.com/auth_service/internal/migrations"
+	"example.com/auth_service/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const migrateUsage = `usage: auth_service migrate <up|down|status> [flags]
+
+  up              apply all pending migrations
+  down -steps N   revert the N most recent migrations (default 1)
+  status          list migrations and when they were applied`
+
+// runMigrate implements the `migrate` subcommand.
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("migrate "+action, flag.ExitOnError)
+	steps := fs.Int("steps", 1, "number of migrations to revert (down only)")
+	var cfgOpts config.Options
+	cfgOpts.RegisterFlags(fs)
+	_ = fs.Parse(args[1:]) // ExitOnError handles failures
+
+	cfg, err := config.LoadWith(cfgOpts)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	appLogger, err := logger.New(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer func() { _ = appLogger.Sync() }()
+
+	db, err := database.Connect(context.Background(), cfg.Database, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	migrator, err := migrations.NewMigrator(db, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to load migrations", zap.Error(err))
+	}
+
+	ctx := context.Background()
+	switch action {
+	case "up":
+		n, err := migrator.Up(ctx)
+		if err != nil {
+			appLogger.Fatal("Migration failed", zap.Error(err))
+		}
+		appLogger.Info("Migrations applied", zap.Int("count", n))
+	case "down":
+		n, err := migrator.Down(ctx, *steps)
+		if err != nil {
+			appLogger.Fatal("Migration rollback failed", zap.Error(err))
+		}
+		appLogger.Info("Migrations reverted", zap.Int("count", n))
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			appLogger.Fatal("Failed to read migration status", zap.Error(err))
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(statuses); err != nil {
+			appLogger.Fatal("Failed to write migration status", zap.Error(err))
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
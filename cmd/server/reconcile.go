@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/internal/database"
+	"example.com/auth_service/internal/reconcile"
+	"example.com/auth_service/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// runReconcile implements the `reconcile` subcommand: a single reconciliation
+// pass between the S3 bucket and the audio_files table, printed as JSON.
+func runReconcile(args []string) {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", true, "only report orphans, do not delete anything")
+	minAge := fs.Duration("min-age", 0, "ignore objects and rows younger than this (default RECONCILE_MIN_AGE)")
+	var cfgOpts config.Options
+	cfgOpts.RegisterFlags(fs)
+	_ = fs.Parse(args) // ExitOnError handles failures
+
+	cfg, err := config.LoadWith(cfgOpts)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if *minAge == 0 {
+		*minAge = cfg.Reconcile.MinAge
+	}
+
+	appLogger, err := logger.New(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer func() { _ = appLogger.Sync() }()
+
+	db, err := database.ConnectCluster(context.Background(), cfg.Database, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	blobStore, err := newBlobStore(cfg, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize blob store", zap.Error(err))
+	}
+
+	reconciler := reconcile.NewReconciler(blobStore,
+		database.NewAudioRepository(db, appLogger),
+		database.NewUsageRepository(db, appLogger),
+		database.NewTxManager(db),
+		appLogger)
+	report, err := reconciler.Run(context.Background(), reconcile.Options{DryRun: *dryRun, MinAge: *minAge})
+	if err != nil {
+		appLogger.Fatal("Reconciliation failed", zap.Error(err))
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		appLogger.Fatal("Failed to write reconciliation report", zap.Error(err))
+	}
+}
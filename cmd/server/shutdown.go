@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"example.com/auth_service/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// backgroundJobs tracks background goroutines so shutdown can wait for them.
+type backgroundJobs struct {
+	wg sync.WaitGroup
+}
+
+// Go runs fn in a tracked goroutine.
+func (b *backgroundJobs) Go(fn func()) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		fn()
+	}()
+}
+
+// Wait blocks until every job has returned or ctx is done.
+func (b *backgroundJobs) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shutdown stops srv from accepting connections and drains in-flight requests,
+// then stops the background jobs and waits for them, all before ctx expires.
+// Whatever is still running at the deadline is cut off.
+func shutdown(ctx context.Context, srv *http.Server, cancelJobs context.CancelFunc, jobs *backgroundJobs, appLogger *logger.Logger) {
+	appLogger.Info("Shutting down, draining in-flight requests")
+	if err := srv.Shutdown(ctx); err != nil {
+		appLogger.Warn("Requests still in flight at the shutdown deadline, closing connections", zap.Error(err))
+		_ = srv.Close()
+	}
+
+	cancelJobs()
+	if err := jobs.Wait(ctx); err != nil {
+		appLogger.Warn("Background jobs still running at the shutdown deadline", zap.Error(err))
+	}
+	appLogger.Info("Shutdown complete")
+}
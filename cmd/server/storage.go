@@ -0,0 +1,21 @@
+package main
+
+import (
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/internal/s3service"
+	"example.com/auth_service/internal/storage"
+	"example.com/auth_service/pkg/logger"
+)
+
+// newBlobStore builds the blob store selected by cfg.Storage.Backend.
+func newBlobStore(cfg *config.Config, appLogger *logger.Logger) (storage.BlobStore, error) {
+	switch cfg.Storage.Backend {
+	case "local":
+		return storage.NewLocalStore(cfg.Storage.LocalDir, appLogger)
+	case "memory":
+		appLogger.Warn("Using in-memory blob store; uploads are lost on restart")
+		return storage.NewMemoryStore(), nil
+	default:
+		return s3service.NewS3Service(cfg.S3, appLogger)
+	}
+}
@@ -0,0 +1,124 @@
+// Package apierror defines the errors returned by the HTTP API and renders
+// them as RFC 7807 problem details with a stable, machine-readable code.
+package apierror
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Code identifies an error condition. Codes are part of the API contract:
+// clients branch on them, so never change or reuse an existing one.
+type Code string
+
+const (
+	CodeInvalidRequest      Code = "INVALID_REQUEST"   // Malformed body or parameters
+	CodeValidationFailed    Code = "VALIDATION_FAILED" // Well-formed but invalid fields; see errors
+	CodeUnauthorized        Code = "UNAUTHORIZED"      // Missing, invalid or expired token
+	CodeInvalidCredentials  Code = "INVALID_CREDENTIALS"
+	CodeForbidden           Code = "FORBIDDEN"
+	CodeAccountDisabled     Code = "ACCOUNT_DISABLED" // Disabled by an admin
+	CodeNotFound            Code = "NOT_FOUND"
+	CodeMethodNotAllowed    Code = "METHOD_NOT_ALLOWED"
+	CodeEmailTaken          Code = "EMAIL_TAKEN"
+	CodeUsernameTaken       Code = "USERNAME_TAKEN"
+	CodeVerificationInvalid Code = "VERIFICATION_TOKEN_INVALID" // Unknown, expired or superseded email verification token
+	CodeAudioTooLarge       Code = "AUDIO_TOO_LARGE"
+	CodeAudioFormat         Code = "UNSUPPORTED_AUDIO_FORMAT"
+	CodeStorageQuota        Code = "STORAGE_QUOTA_EXCEEDED" // Stored bytes or file count limit
+	CodeUploadRateLimited   Code = "UPLOAD_RATE_LIMITED"    // Uploads per period limit
+	CodeRateLimited         Code = "RATE_LIMITED"           // Too many requests; see Retry-After
+	CodeIdempotencyReused   Code = "IDEMPOTENCY_KEY_REUSED" // Same Idempotency-Key, different request
+	CodeIdempotencyInUse    Code = "IDEMPOTENCY_KEY_IN_USE" // The first request with the key is still running
+	CodeInternal            Code = "INTERNAL_ERROR"
+)
+
+// FieldError describes one invalid request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an API error. Detail is shown to clients and must not contain
+// internals; the underlying cause goes in Err, which is logged but never rendered.
+type Error struct {
+	Status     int
+	Code       Code
+	Detail     string
+	Fields     []FieldError
+	Extensions map[string]any    // Extra problem members, e.g. the exceeded limit
+	Headers    map[string]string // Extra response headers, e.g. Retry-After
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Detail, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates an Error with the given status, code and client-facing detail.
+func New(status int, code Code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// WithCause records the underlying error for logging.
+func (e *Error) WithCause(err error) *Error {
+	e.Err = err
+	return e
+}
+
+// WithExtension adds a member to the problem document.
+func (e *Error) WithExtension(key string, value any) *Error {
+	if e.Extensions == nil {
+		e.Extensions = make(map[string]any)
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+// WithHeader sets a response header when the error is rendered.
+func (e *Error) WithHeader(key, value string) *Error {
+	if e.Headers == nil {
+		e.Headers = make(map[string]string)
+	}
+	e.Headers[key] = value
+	return e
+}
+
+// InvalidRequest reports a request that could not be parsed.
+func InvalidRequest(detail string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidRequest, detail)
+}
+
+// ValidationFailed reports invalid fields found by the handler rather than by binding.
+func ValidationFailed(fields ...FieldError) *Error {
+	e := New(http.StatusUnprocessableEntity, CodeValidationFailed, "One or more fields are invalid.")
+	e.Fields = fields
+	return e
+}
+
+// Unauthorized reports a missing or invalid credential.
+func Unauthorized(detail string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, detail)
+}
+
+// Forbidden reports an authenticated caller without permission.
+func Forbidden(detail string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, detail)
+}
+
+// NotFound reports a missing resource.
+func NotFound(detail string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, detail)
+}
+
+// Internal wraps an unexpected failure. Clients only see detail.
+func Internal(detail string, err error) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, detail).WithCause(err)
+}
@@ -0,0 +1,118 @@
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"example.com/auth_service/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ContentType is the media type of problem responses (RFC 7807).
+const ContentType = "application/problem+json"
+
+// Problem is the RFC 7807 response body, extended with the error code,
+// the request ID and per-field validation errors.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Handler adapts a handler that returns an error to gin. A returned error is
+// recorded on the context and rendered by Middleware.
+func Handler(fn func(c *gin.Context) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := fn(c); err != nil {
+			_ = c.Error(err)
+			c.Abort()
+		}
+	}
+}
+
+// Abort records err and stops the handler chain. Middleware uses it where a
+// handler would return the error.
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// Middleware renders the last error recorded on the context as a problem
+// response, unless a response has already been written. Errors that are not
+// *Error become a generic 500. Server errors are logged with their cause.
+func Middleware(appLogger *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		last := c.Errors.Last()
+		if last == nil || c.Writer.Written() {
+			return
+		}
+
+		var apiErr *Error
+		if !errors.As(last.Err, &apiErr) {
+			apiErr = Internal("An unexpected error occurred.", last.Err)
+		}
+		if apiErr.Status >= http.StatusInternalServerError {
+			appLogger.FromContext(c.Request.Context()).Error("Request failed",
+				zap.String("code", string(apiErr.Code)),
+				zap.Error(apiErr))
+		}
+		Render(c, apiErr)
+	}
+}
+
+// StatusOf returns the status err is rendered with.
+func StatusOf(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Status
+	}
+	return http.StatusInternalServerError
+}
+
+// Render writes e as a problem response.
+func Render(c *gin.Context, e *Error) {
+	for k, v := range e.Headers {
+		c.Header(k, v)
+	}
+	body := gin.H{}
+	for k, v := range e.Extensions {
+		body[k] = v
+	}
+	// Standard members are written last so extensions cannot override them.
+	p := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Detail,
+		Instance:  c.Request.URL.Path,
+		Code:      e.Code,
+		RequestID: logger.RequestID(c.Request.Context()),
+		Errors:    e.Fields,
+	}
+	body["type"] = p.Type
+	body["title"] = p.Title
+	body["status"] = p.Status
+	body["code"] = p.Code
+	if p.Detail != "" {
+		body["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		body["instance"] = p.Instance
+	}
+	if p.RequestID != "" {
+		body["request_id"] = p.RequestID
+	}
+	if len(p.Errors) > 0 {
+		body["errors"] = p.Errors
+	}
+	c.Header("Content-Type", ContentType)
+	c.JSON(e.Status, body)
+}
@@ -0,0 +1,85 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// UseJSONFieldNames makes gin's validator report fields by their JSON name
+// (e.g. "email" rather than "Email"), so FieldError.Field matches the request body.
+// Call it once at startup, before serving requests.
+func UseJSONFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+}
+
+// FromBinding converts an error from gin's ShouldBind* into an API error:
+// per-field messages for validation failures, and a generic message for bodies
+// that are not valid JSON, so parser internals never reach the client.
+func FromBinding(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{Field: fe.Field(), Message: fieldMessage(fe)})
+		}
+		e := New(http.StatusUnprocessableEntity, CodeValidationFailed, "One or more fields are invalid.")
+		e.Fields = fields
+		return e.WithCause(err)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		e := New(http.StatusUnprocessableEntity, CodeValidationFailed, "One or more fields are invalid.")
+		e.Fields = []FieldError{{Field: typeErr.Field, Message: "has the wrong type, expected " + typeErr.Type.String()}}
+		return e.WithCause(err)
+	}
+	if errors.Is(err, io.EOF) {
+		return InvalidRequest("Request body is empty.").WithCause(err)
+	}
+	return InvalidRequest("Request body is not valid JSON.").WithCause(err)
+}
+
+// fieldMessage renders one validation failure in plain English.
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	default:
+		return fmt.Sprintf("failed the %q check", fe.Tag())
+	}
+}
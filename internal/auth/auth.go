@@ -1,6 +1,10 @@
 package auth
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
 	"time"
 
 	"example.com/auth_service/internal/models" // Import models for UserRepository
@@ -9,9 +13,28 @@ import (
 	// "your_project_module_path/internal/models"
 )
 
+// ErrSessionRevoked is returned by CheckSession for tokens issued before the
+// user's sessions were invalidated, or whose user is deleted or being erased.
+var ErrSessionRevoked = errors.New("session revoked")
+
+// ErrAccountDisabled is returned by CheckSession for users disabled by an admin.
+var ErrAccountDisabled = errors.New("account disabled")
+
+// tokenTimePrecision is the precision of token issue times and of
+// sessions_valid_after. Microseconds match Postgres timestamps.
+const tokenTimePrecision = time.Microsecond
+
 // AuthService provides authentication related functionalities.
+//
+// NewAuthService sets golang-jwt's process-wide jwt.TimePrecision to
+// tokenTimePrecision. The default, whole seconds, would let a token issued in
+// the second before sessions_valid_after survive it. Tokens issued with whole
+// seconds still parse. Other users of golang-jwt in the process are affected.
 type AuthService struct {
+	mu               sync.RWMutex // Guards the keys, which rotate at runtime
 	jwtSecretKey     string
+	previousKey      string    // Key before the last rotation, still accepted for verification
+	previousKeyUntil time.Time // When tokens signed with previousKey have all expired
 	jwtExpirationHrs int
 	userRepo         models.UserRepository // Use UserRepository from models package
 	// userRepo       UserRepository // Define UserRepository interface later
@@ -26,6 +49,7 @@ type AuthService struct {
 
 // NewAuthService creates a new AuthService.
 func NewAuthService(jwtSecret string, jwtExpHrs int, userRepo models.UserRepository) *AuthService {
+	jwt.TimePrecision = tokenTimePrecision
 	return &AuthService{
 		jwtSecretKey:     jwtSecret,
 		jwtExpirationHrs: jwtExpHrs,
@@ -53,15 +77,17 @@ func CheckPasswordHash(password, hash string) bool {
 type Claims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role,omitempty"` // Empty in tokens issued before roles existed
 	jwt.RegisteredClaims
 }
 
 // GenerateJWT generates a new JWT for a given user.
-func (s *AuthService) GenerateJWT(userID, email string) (string, error) {
+func (s *AuthService) GenerateJWT(userID, email, role string) (string, error) {
 	expirationTime := time.Now().Add(time.Duration(s.jwtExpirationHrs) * time.Hour)
 	claims := &Claims{
 		UserID: userID,
 		Email:  email,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -70,23 +96,90 @@ func (s *AuthService) GenerateJWT(userID, email string) (string, error) {
 		},
 	}
 
+	s.mu.RLock()
+	key := s.jwtSecretKey
+	s.mu.RUnlock()
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.jwtSecretKey))
+	tokenString, err := token.SignedString([]byte(key))
 	if err != nil {
 		return "", err
 	}
 	return tokenString, nil
 }
 
-// ValidateJWT validates a JWT string.
+// RotateSecret makes key the signing key. Tokens signed with the old key stay
+// valid until they expire, so a rotation does not log anyone out.
+func (s *AuthService) RotateSecret(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key == s.jwtSecretKey {
+		return
+	}
+	s.previousKey = s.jwtSecretKey
+	s.previousKeyUntil = time.Now().Add(time.Duration(s.jwtExpirationHrs) * time.Hour)
+	s.jwtSecretKey = key
+}
+
+// ValidateJWT validates a JWT string against the current key and, after a
+// rotation, the previous one.
 func (s *AuthService) ValidateJWT(tokenString string) (*Claims, error) {
+	s.mu.RLock()
+	key, previous := s.jwtSecretKey, s.previousKey
+	if time.Now().After(s.previousKeyUntil) {
+		previous = ""
+	}
+	s.mu.RUnlock()
+
+	claims, err := parseJWT(tokenString, key)
+	if errors.Is(err, jwt.ErrTokenSignatureInvalid) && previous != "" {
+		claims, err = parseJWT(tokenString, previous)
+	}
+	return claims, err
+}
+
+// SessionCutoff returns the sessions_valid_after that revokes every token
+// issued until now. Revocations must use it rather than the database clock:
+// issue times come from this clock, and any skew would otherwise reject fresh
+// tokens or keep revoked ones.
+func SessionCutoff() time.Time {
+	return time.Now().Truncate(tokenTimePrecision)
+}
+
+// CheckSession rejects valid tokens that have since been revoked: tokens of
+// deleted or disabled users, and tokens issued before the user's
+// sessions_valid_after. It sets claims.Role to the user's current role, so a
+// role change applies without logging in again.
+func (s *AuthService) CheckSession(ctx context.Context, claims *Claims) error {
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSessionRevoked
+	}
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt != nil {
+		return ErrSessionRevoked
+	}
+	if user.DisabledAt != nil {
+		return ErrAccountDisabled
+	}
+	// Both have microsecond precision, so even tokens issued earlier in the same second are revoked
+	if user.SessionsValidAfter != nil && (claims.IssuedAt == nil || claims.IssuedAt.Time.Before(*user.SessionsValidAfter)) {
+		return ErrSessionRevoked
+	}
+	claims.Role = user.Role
+	return nil
+}
+
+func parseJWT(tokenString, key string) (*Claims, error) {
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		// Check the signing method
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, jwt.ErrSignatureInvalid
 		}
-		return []byte(s.jwtSecretKey), nil
+		return []byte(key), nil
 	})
 
 	if err != nil {
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/auth_service/internal/models"
+)
+
+// stubUsers serves one user; other UserRepository methods are not used by CheckSession.
+type stubUsers struct {
+	models.UserRepository
+	user *models.User
+}
+
+func (r *stubUsers) GetUserByID(context.Context, string) (*models.User, error) {
+	return r.user, nil
+}
+
+func TestCheckSessionRevokesTokensFromTheSameSecond(t *testing.T) {
+	user := &models.User{ID: "00000000-0000-0000-0000-000000000001", Email: "a@example.com", Role: models.RoleUser}
+	s := NewAuthService("test-secret-key-that-is-long-enough", 1, &stubUsers{user: user})
+
+	oldToken, err := s.GenerateJWT(user.ID, user.Email, user.Role)
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	validAfter := SessionCutoff()
+	user.SessionsValidAfter = &validAfter
+	time.Sleep(time.Millisecond)
+	newToken, err := s.GenerateJWT(user.ID, user.Email, user.Role)
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "issued before", token: oldToken, wantErr: ErrSessionRevoked},
+		{name: "issued after", token: newToken, wantErr: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := s.ValidateJWT(tt.token)
+			if err != nil {
+				t.Fatalf("ValidateJWT: %v", err)
+			}
+			if err := s.CheckSession(context.Background(), claims); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckSession = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
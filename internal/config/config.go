@@ -1,22 +1,68 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"net/url"
 	"os"
 	"strconv"
-	// "github.com/joho/godotenv"
-	// "your_project_module_path/internal/database" // If DBConfig is defined there
+	"strings"
+	"time"
+
+	"example.com/auth_service/internal/models"
+)
+
+// Environments accepted in APP_ENV. Production refuses insecure defaults.
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// Default secrets that must never reach production.
+const (
+	defaultJWTSecret  = "a-very-secret-key-that-should-be-long-and-random"
+	defaultDBPassword = "password"
+	minJWTSecretLen   = 32
 )
 
 // Config holds all configuration for the application.
-// Values are loaded from environment variables.
+// Values come from defaults, a YAML file, environment variables and flags; see Load.
 type Config struct {
-	AppPort   string
-	Database  DatabaseConfig // Renamed from internal/database.DBConfig to avoid import cycle if that was moved here
-	JWT       JWTConfig
-	LogLevel  string   // e.g., "debug", "info", "warn", "error"
-	LogFormat string   // e.g., "json", "console"
-	S3        S3Config // New S3 config section
+	Env         string // EnvDevelopment or EnvProduction
+	AppPort     string
+	HTTP        HTTPConfig
+	Database    DatabaseConfig // Renamed from internal/database.DBConfig to avoid import cycle if that was moved here
+	JWT         JWTConfig
+	LogLevel    string   // e.g., "debug", "info", "warn", "error"
+	LogFormat   string   // e.g., "json", "console"
+	S3          S3Config // New S3 config section
+	Storage     StorageConfig
+	Reconcile   ReconcileConfig
+	Quota       QuotaConfig
+	Retention   RetentionConfig
+	Health      HealthConfig
+	Tracing     TracingConfig
+	Secrets     SecretsConfig
+	CORS        CORSConfig
+	Security    SecurityConfig
+	RateLimit   RateLimitConfig
+	Idempotency IdempotencyConfig
+	Email       EmailConfig
+	Erasure     ErasureConfig
+
+	settings []Setting // Every resolved setting, for Print
+}
+
+// HTTPConfig holds HTTP server timeouts.
+type HTTPConfig struct {
+	ReadTimeout       time.Duration // Reading the whole request, including upload bodies
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration // Keep-alive connections
+	ShutdownTimeout   time.Duration // Deadline for draining requests and background jobs on shutdown
 }
 
 // DatabaseConfig holds database connection parameters.
@@ -30,6 +76,19 @@ type DatabaseConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+
+	MigrateOnStartup bool // Apply pending migrations when the server starts
+
+	MaxOpenConns     int           // 0 means unlimited
+	MaxIdleConns     int           // Idle connections kept in the pool
+	ConnMaxLifetime  time.Duration // Recycle connections older than this; 0 keeps them forever
+	ConnMaxIdleTime  time.Duration // Close connections idle for longer than this; 0 keeps them
+	StatementTimeout time.Duration // Server-side statement_timeout for every session; 0 disables it
+	ConnectTimeout   time.Duration // How long startup keeps retrying while Postgres is unavailable
+
+	Replicas             []string      // Read replicas as host[:port]; they share the primary's credentials
+	ReplicaCheckInterval time.Duration // How often replica health is re-checked
+	ReadYourWritesWindow time.Duration // After a mutation, the client's reads go to the primary for this long
 }
 
 // JWTConfig holds JWT related configuration.
@@ -48,47 +107,466 @@ type S3Config struct {
 	UsePathStyle    bool // For MinIO, this is often true
 }
 
-// Load loads configuration from environment variables.
-// It loads .env file first if present.
+// StorageConfig selects the blob store backend.
+type StorageConfig struct {
+	Backend  string // "s3" (default), "local" or "memory"
+	LocalDir string // Root directory for the "local" backend
+}
+
+// ReconcileConfig holds settings for the S3/database orphan reconciler.
+type ReconcileConfig struct {
+	Interval time.Duration // How often the background reconciler runs; 0 disables it
+	DryRun   bool          // Only report orphans, never delete them
+	MinAge   time.Duration // Ignore objects and rows younger than this
+}
+
+// RetentionConfig holds settings for the automatic expiry of stored audio.
+type RetentionConfig struct {
+	DefaultDays   int           // Applied to users without retention_days; 0 keeps audio forever
+	SweepInterval time.Duration // How often the sweeper runs; 0 disables it
+	KeepMetadata  bool          // Keep audio_files rows (anonymised) instead of deleting them
+	BatchSize     int           // Files purged per query
+}
+
+// HealthConfig holds settings for the readiness checks.
+type HealthConfig struct {
+	CheckTimeout time.Duration // Timeout for each dependency check
+	CacheTTL     time.Duration // How long a check result is reused
+}
+
+// TracingConfig holds OpenTelemetry settings. The OTLP endpoint and headers
+// are read by the exporter from the standard OTEL_EXPORTER_OTLP_* variables.
+type TracingConfig struct {
+	Exporter    string  // "none" (default), "otlp" or "stdout"
+	ServiceName string  // service.name resource attribute
+	SampleRatio float64 // Fraction of new traces sampled; incoming sampled traces are always kept
+}
+
+// CORSConfig holds the cross-origin policy for browser clients.
+type CORSConfig struct {
+	AllowOrigins     []string // Exact origins, "https://*.example.com" for any subdomain, or "*"
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool          // Cookies and Authorization from the browser; not allowed with "*"
+	MaxAge           time.Duration // How long browsers may cache a preflight response
+}
+
+// SecurityConfig holds response security headers and proxy trust.
+type SecurityConfig struct {
+	HSTSMaxAge     time.Duration // Strict-Transport-Security max-age; 0 omits the header (serve over TLS before enabling)
+	TrustedProxies []string      // IPs or CIDRs whose X-Forwarded-For is believed for the client IP; empty trusts none
+}
+
+// RateLimit is a token bucket: up to Burst requests at once, refilled at
+// Burst per Period. A zero Burst disables the limit.
+type RateLimit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// RateLimitConfig holds per-route request limits for the API.
+type RateLimitConfig struct {
+	Backend  string // "memory" (default; per instance) or "redis" (shared by all instances)
+	RedisURL string // redis://[:password@]host:port/db, for the "redis" backend
+
+	Register RateLimit // POST /users/register, per client IP
+	Login    RateLimit // POST /users/login, per client IP
+	Upload   RateLimit // POST /audio/upload, per user
+	Export   RateLimit // GET /users/me/export, per user
+	Default  RateLimit // Other authenticated routes, per user
+}
+
+// IdempotencyConfig controls how Idempotency-Key headers are remembered.
+type IdempotencyConfig struct {
+	TTL         time.Duration // How long a key and its stored response are kept
+	LockTimeout time.Duration // After this, a key whose request never finished can be reused
+}
+
+// EmailConfig holds settings for emails sent to users.
+type EmailConfig struct {
+	VerificationURL string        // Frontend page that confirms an email change; the token is appended as ?token=
+	VerificationTTL time.Duration // How long an email change can be confirmed
+
+	Sender       string // "log" (default; development only, nothing is delivered) or "smtp"
+	From         string // Sender address, e.g. "Audio Service <no-reply@example.com>"
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string // Empty to send without authentication
+	SMTPPassword string
+}
+
+// ErasureConfig controls the background job that erases deleted accounts.
+type ErasureConfig struct {
+	Interval time.Duration // How often pending erasures are processed
+}
+
+// SecretsConfig describes secrets read from files (DB_PASSWORD_FILE etc.),
+// which are polled so rotated values take effect without a restart.
+type SecretsConfig struct {
+	Files        map[string]string // Secret key (e.g. "DB_PASSWORD") -> file path
+	PollInterval time.Duration     // How often the files are re-read; 0 disables reloading
+}
+
+// QuotaConfig holds per-role upload quotas.
+type QuotaConfig struct {
+	Period  time.Duration                 // Length of the window for MaxUploadsPerPeriod
+	Default models.QuotaLimits            // Applied to roles without an override
+	Roles   map[string]models.QuotaLimits // Per-role overrides, keyed by users.role
+}
+
+// LimitsFor returns the quota limits for a role.
+func (q QuotaConfig) LimitsFor(role string) models.QuotaLimits {
+	if limits, ok := q.Roles[role]; ok {
+		return limits
+	}
+	return q.Default
+}
+
+// Load loads configuration from the environment and the file named by CONFIG_FILE, if any.
 func Load() (*Config, error) {
-	// Attempt to load .env file. Errors are not fatal here, as env vars might be set directly.
-	// if err := godotenv.Load(); err != nil {
-	// 	fmt.Println("No .env file found or error loading it, relying on OS environment variables.")
-	// }
-
-	appPort := getEnv("GO_APP_PORT", "8080")
-
-	dbHost := getEnv("DB_HOST", "localhost")
-	dbPort := getEnv("DB_PORT", "5432")
-	dbUser := getEnv("DB_USER", "postgres")
-	dbPassword := getEnv("DB_PASSWORD", "password")
-	dbName := getEnv("DB_NAME", "auth_db")
-	dbSSLMode := getEnv("DB_SSLMODE", "disable")
-
-	jwtSecret := getEnv("JWT_SECRET_KEY", "a-very-secret-key-that-should-be-long-and-random")
-	jwtExpStr := getEnv("JWT_EXPIRATION_HOURS", "24")
+	return LoadWith(Options{File: os.Getenv("CONFIG_FILE")})
+}
+
+// LoadWith loads configuration. Each setting is taken from the first source
+// that sets it: command-line overrides, then environment variables, then the
+// config file, then the built-in default. In production it fails if any
+// insecure default is still in place.
+func LoadWith(opts Options) (*Config, error) {
+	r, err := newResolver(opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := load(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := errors.Join(r.errs...); err != nil {
+		return nil, err
+	}
+	cfg.Secrets.Files = r.secretFiles
+	if unknown := r.unknownKeys(); len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown config keys: %s", strings.Join(unknown, ", "))
+	}
+	cfg.settings = r.settings
+	if cfg.Env == EnvProduction && !opts.AllowInsecure {
+		if problems := cfg.InsecureSettings(); len(problems) > 0 {
+			return nil, fmt.Errorf("refusing to start in production: %s", strings.Join(problems, "; "))
+		}
+	}
+	return cfg, nil
+}
+
+func load(r *resolver) (*Config, error) {
+	appEnv := r.get("APP_ENV", EnvDevelopment)
+	switch appEnv {
+	case EnvDevelopment, EnvProduction:
+	default:
+		return nil, fmt.Errorf("invalid APP_ENV value: %s (want development or production)", appEnv)
+	}
+	appPort := r.get("GO_APP_PORT", "8080")
+	httpReadTimeout, err := time.ParseDuration(r.get("HTTP_READ_TIMEOUT", "5m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP_READ_TIMEOUT: %w", err)
+	}
+	httpReadHeaderTimeout, err := time.ParseDuration(r.get("HTTP_READ_HEADER_TIMEOUT", "10s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP_READ_HEADER_TIMEOUT: %w", err)
+	}
+	httpWriteTimeout, err := time.ParseDuration(r.get("HTTP_WRITE_TIMEOUT", "5m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP_WRITE_TIMEOUT: %w", err)
+	}
+	httpIdleTimeout, err := time.ParseDuration(r.get("HTTP_IDLE_TIMEOUT", "2m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP_IDLE_TIMEOUT: %w", err)
+	}
+	httpShutdownTimeout, err := time.ParseDuration(r.get("HTTP_SHUTDOWN_TIMEOUT", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP_SHUTDOWN_TIMEOUT: %w", err)
+	}
+
+	dbHost := r.get("DB_HOST", "localhost")
+	dbPort := r.get("DB_PORT", "5432")
+	dbUser := r.get("DB_USER", "postgres")
+	dbPassword := r.get("DB_PASSWORD", defaultDBPassword)
+	dbName := r.get("DB_NAME", "auth_db")
+	dbSSLMode := r.get("DB_SSLMODE", "disable")
+	dbMigrateOnStartup, err := strconv.ParseBool(r.get("DB_MIGRATE_ON_STARTUP", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_MIGRATE_ON_STARTUP: %w", err)
+	}
+	dbMaxOpenConns, err := strconv.Atoi(r.get("DB_MAX_OPEN_CONNS", "25"))
+	if err != nil || dbMaxOpenConns < 0 {
+		return nil, fmt.Errorf("invalid DB_MAX_OPEN_CONNS: must be a non-negative integer")
+	}
+	dbMaxIdleConns, err := strconv.Atoi(r.get("DB_MAX_IDLE_CONNS", "10"))
+	if err != nil || dbMaxIdleConns < 0 {
+		return nil, fmt.Errorf("invalid DB_MAX_IDLE_CONNS: must be a non-negative integer")
+	}
+	dbConnMaxLifetime, err := time.ParseDuration(r.get("DB_CONN_MAX_LIFETIME", "30m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_CONN_MAX_LIFETIME: %w", err)
+	}
+	dbConnMaxIdleTime, err := time.ParseDuration(r.get("DB_CONN_MAX_IDLE_TIME", "5m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_CONN_MAX_IDLE_TIME: %w", err)
+	}
+	dbStatementTimeout, err := time.ParseDuration(r.get("DB_STATEMENT_TIMEOUT", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_STATEMENT_TIMEOUT: %w", err)
+	}
+	dbConnectTimeout, err := time.ParseDuration(r.get("DB_CONNECT_TIMEOUT", "60s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_CONNECT_TIMEOUT: %w", err)
+	}
+	dbReplicas := splitList(r.get("DB_REPLICAS", ""))
+	dbReplicaCheckInterval, err := time.ParseDuration(r.get("DB_REPLICA_CHECK_INTERVAL", "5s"))
+	if err != nil || dbReplicaCheckInterval <= 0 {
+		return nil, fmt.Errorf("invalid DB_REPLICA_CHECK_INTERVAL: must be a positive duration")
+	}
+	dbReadYourWritesWindow, err := time.ParseDuration(r.get("DB_READ_YOUR_WRITES_WINDOW", "5s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_READ_YOUR_WRITES_WINDOW: %w", err)
+	}
+
+	jwtSecret := r.get("JWT_SECRET_KEY", defaultJWTSecret)
+	jwtExpStr := r.get("JWT_EXPIRATION_HOURS", "24")
 	jwtExp, err := strconv.Atoi(jwtExpStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid JWT_EXPIRATION_HOURS: %w", err)
 	}
 
-	logLevel := getEnv("LOG_LEVEL", "info")
-	logFormat := getEnv("LOG_FORMAT", "console") // "json" or "console"
+	logLevel := r.get("LOG_LEVEL", "info")
+	logFormat := r.get("LOG_FORMAT", "console") // "json" or "console"
 
 	// S3/MinIO Config
-	s3Endpoint := getEnv("S3_ENDPOINT", "http://localhost:9000")
-	s3AccessKeyID := getEnv("S3_ACCESS_KEY_ID", "")
-	s3SecretAccessKey := getEnv("S3_SECRET_ACCESS_KEY", "")
-	s3BucketName := getEnv("S3_BUCKET_NAME", "default-bucket")
-	s3Region := getEnv("S3_REGION", "us-east-1")
-	s3UsePathStyleStr := getEnv("S3_USE_PATH_STYLE", "false")
+	s3Endpoint := r.get("S3_ENDPOINT", "http://localhost:9000")
+	s3AccessKeyID := r.get("S3_ACCESS_KEY_ID", "")
+	s3SecretAccessKey := r.get("S3_SECRET_ACCESS_KEY", "")
+	s3BucketName := r.get("S3_BUCKET_NAME", "default-bucket")
+	s3Region := r.get("S3_REGION", "us-east-1")
+	s3UsePathStyleStr := r.get("S3_USE_PATH_STYLE", "false")
 	s3UsePathStyle, err := strconv.ParseBool(s3UsePathStyleStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid S3_USE_PATH_STYLE value: %s, error: %w", s3UsePathStyleStr, err)
 	}
 
+	// Blob storage backend
+	storageBackend := r.get("STORAGE_BACKEND", "s3")
+	switch storageBackend {
+	case "s3", "local", "memory":
+	default:
+		return nil, fmt.Errorf("invalid STORAGE_BACKEND value: %s (want s3, local or memory)", storageBackend)
+	}
+	storageLocalDir := r.get("STORAGE_LOCAL_DIR", "./data/blobs")
+
+	// Reconciler Config
+	reconcileInterval, err := time.ParseDuration(r.get("RECONCILE_INTERVAL", "0s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RECONCILE_INTERVAL: %w", err)
+	}
+	reconcileDryRun, err := strconv.ParseBool(r.get("RECONCILE_DRY_RUN", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RECONCILE_DRY_RUN: %w", err)
+	}
+	reconcileMinAge, err := time.ParseDuration(r.get("RECONCILE_MIN_AGE", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RECONCILE_MIN_AGE: %w", err)
+	}
+
+	// Quota Config
+	quotaPeriod, err := time.ParseDuration(r.get("QUOTA_PERIOD", "24h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid QUOTA_PERIOD: %w", err)
+	}
+	quotaDefault, err := loadQuotaLimits(r, "QUOTA", models.QuotaLimits{
+		MaxBytes:            500 * 1024 * 1024, // 500 MB
+		MaxFiles:            1000,
+		MaxUploadsPerPeriod: 100,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// QUOTA_ROLES=admin,premium enables QUOTA_ADMIN_MAX_BYTES etc.; unset values fall back to the defaults.
+	quotaRoles := make(map[string]models.QuotaLimits)
+	for _, role := range strings.Split(r.get("QUOTA_ROLES", ""), ",") {
+		role = strings.TrimSpace(role)
+		if role == "" {
+			continue
+		}
+		limits, err := loadQuotaLimits(r, "QUOTA_"+strings.ToUpper(role), quotaDefault)
+		if err != nil {
+			return nil, err
+		}
+		quotaRoles[role] = limits
+	}
+
+	// Retention Config
+	retentionDays, err := strconv.Atoi(r.get("RETENTION_DEFAULT_DAYS", "90"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RETENTION_DEFAULT_DAYS: %w", err)
+	}
+	retentionInterval, err := time.ParseDuration(r.get("RETENTION_SWEEP_INTERVAL", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RETENTION_SWEEP_INTERVAL: %w", err)
+	}
+	retentionKeepMetadata, err := strconv.ParseBool(r.get("RETENTION_KEEP_METADATA", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RETENTION_KEEP_METADATA: %w", err)
+	}
+	retentionBatchSize, err := strconv.Atoi(r.get("RETENTION_BATCH_SIZE", "100"))
+	if err != nil || retentionBatchSize <= 0 {
+		return nil, fmt.Errorf("invalid RETENTION_BATCH_SIZE: must be a positive integer")
+	}
+
+	// Health check Config
+	healthCheckTimeout, err := time.ParseDuration(r.get("HEALTH_CHECK_TIMEOUT", "2s"))
+	if err != nil || healthCheckTimeout <= 0 {
+		return nil, fmt.Errorf("invalid HEALTH_CHECK_TIMEOUT: must be a positive duration")
+	}
+	healthCacheTTL, err := time.ParseDuration(r.get("HEALTH_CACHE_TTL", "5s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid HEALTH_CACHE_TTL: %w", err)
+	}
+
+	// Tracing Config
+	tracingExporter := r.get("TRACING_EXPORTER", "none")
+	switch tracingExporter {
+	case "none", "otlp", "stdout":
+	default:
+		return nil, fmt.Errorf("invalid TRACING_EXPORTER value: %s (want none, otlp or stdout)", tracingExporter)
+	}
+	tracingSampleRatio, err := strconv.ParseFloat(r.get("TRACING_SAMPLE_RATIO", "1"), 64)
+	if err != nil || tracingSampleRatio < 0 || tracingSampleRatio > 1 {
+		return nil, fmt.Errorf("invalid TRACING_SAMPLE_RATIO: must be between 0 and 1")
+	}
+
+	// CORS Config
+	corsAllowOrigins := splitList(r.get("CORS_ALLOW_ORIGINS", "http://localhost:3000"))
+	corsAllowCredentials, err := strconv.ParseBool(r.get("CORS_ALLOW_CREDENTIALS", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CORS_ALLOW_CREDENTIALS: %w", err)
+	}
+	for _, origin := range corsAllowOrigins {
+		if err := validateOrigin(origin); err != nil {
+			return nil, fmt.Errorf("invalid CORS_ALLOW_ORIGINS: %w", err)
+		}
+		if origin == "*" && corsAllowCredentials {
+			return nil, fmt.Errorf("invalid CORS_ALLOW_ORIGINS: \"*\" cannot be combined with CORS_ALLOW_CREDENTIALS")
+		}
+	}
+	corsMaxAge, err := time.ParseDuration(r.get("CORS_MAX_AGE", "12h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CORS_MAX_AGE: %w", err)
+	}
+
+	// Security Config
+	securityHSTSMaxAge, err := time.ParseDuration(r.get("SECURITY_HSTS_MAX_AGE", "0s"))
+	if err != nil || securityHSTSMaxAge < 0 {
+		return nil, fmt.Errorf("invalid SECURITY_HSTS_MAX_AGE: must be a non-negative duration")
+	}
+	trustedProxies := splitList(r.get("TRUSTED_PROXIES", ""))
+	for _, proxy := range trustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %q is not an IP or CIDR", proxy)
+		}
+	}
+
+	// Rate limit Config
+	rateLimitBackend := r.get("RATE_LIMIT_BACKEND", "memory")
+	switch rateLimitBackend {
+	case "memory", "redis":
+	default:
+		return nil, fmt.Errorf("invalid RATE_LIMIT_BACKEND value: %s (want memory or redis)", rateLimitBackend)
+	}
+	rateLimitRedisURL := r.get("RATE_LIMIT_REDIS_URL", "redis://localhost:6379/0")
+	rateLimitRegister, err := parseRateLimit("RATE_LIMIT_REGISTER", r.get("RATE_LIMIT_REGISTER", "10/1h"))
+	if err != nil {
+		return nil, err
+	}
+	rateLimitLogin, err := parseRateLimit("RATE_LIMIT_LOGIN", r.get("RATE_LIMIT_LOGIN", "20/1m"))
+	if err != nil {
+		return nil, err
+	}
+	rateLimitUpload, err := parseRateLimit("RATE_LIMIT_UPLOAD", r.get("RATE_LIMIT_UPLOAD", "20/1m"))
+	if err != nil {
+		return nil, err
+	}
+	rateLimitExport, err := parseRateLimit("RATE_LIMIT_EXPORT", r.get("RATE_LIMIT_EXPORT", "5/1h"))
+	if err != nil {
+		return nil, err
+	}
+	rateLimitDefault, err := parseRateLimit("RATE_LIMIT_DEFAULT", r.get("RATE_LIMIT_DEFAULT", "300/1m"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Idempotency Config
+	idempotencyTTL, err := time.ParseDuration(r.get("IDEMPOTENCY_TTL", "24h"))
+	if err != nil || idempotencyTTL <= 0 {
+		return nil, fmt.Errorf("invalid IDEMPOTENCY_TTL: must be a positive duration")
+	}
+	idempotencyLockTimeout, err := time.ParseDuration(r.get("IDEMPOTENCY_LOCK_TIMEOUT", "5m"))
+	if err != nil || idempotencyLockTimeout <= 0 {
+		return nil, fmt.Errorf("invalid IDEMPOTENCY_LOCK_TIMEOUT: must be a positive duration")
+	}
+
+	// Email Config
+	emailVerificationURL := r.get("EMAIL_VERIFICATION_URL", "http://localhost:3000/verify-email")
+	if u, err := url.Parse(emailVerificationURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid EMAIL_VERIFICATION_URL: must be an absolute URL")
+	}
+	emailVerificationTTL, err := time.ParseDuration(r.get("EMAIL_VERIFICATION_TTL", "24h"))
+	if err != nil || emailVerificationTTL <= 0 {
+		return nil, fmt.Errorf("invalid EMAIL_VERIFICATION_TTL: must be a positive duration")
+	}
+	emailSender := r.get("EMAIL_SENDER", "log")
+	switch emailSender {
+	case "log", "smtp":
+	default:
+		return nil, fmt.Errorf("invalid EMAIL_SENDER value: %s (want log or smtp)", emailSender)
+	}
+	emailFrom := r.get("EMAIL_FROM", "")
+	smtpHost := r.get("SMTP_HOST", "")
+	smtpPort := r.get("SMTP_PORT", "587")
+	smtpUsername := r.get("SMTP_USERNAME", "")
+	smtpPassword := r.get("SMTP_PASSWORD", "")
+	if emailSender == "smtp" {
+		if smtpHost == "" {
+			return nil, fmt.Errorf("invalid SMTP_HOST: required when EMAIL_SENDER is smtp")
+		}
+		if port, err := strconv.Atoi(smtpPort); err != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid SMTP_PORT: must be a port number")
+		}
+		if _, err := mail.ParseAddress(emailFrom); err != nil {
+			return nil, fmt.Errorf("invalid EMAIL_FROM: must be an email address when EMAIL_SENDER is smtp")
+		}
+	}
+
+	// Erasure Config
+	erasureInterval, err := time.ParseDuration(r.get("ERASURE_INTERVAL", "1m"))
+	// No "disabled" value: DELETE /users/me promises an erasure, so something must carry it out
+	if err != nil || erasureInterval <= 0 {
+		return nil, fmt.Errorf("invalid ERASURE_INTERVAL: must be a positive duration")
+	}
+
+	// Secrets Config
+	secretsPollInterval, err := time.ParseDuration(r.get("SECRETS_POLL_INTERVAL", "30s"))
+	if err != nil || secretsPollInterval < 0 {
+		return nil, fmt.Errorf("invalid SECRETS_POLL_INTERVAL: must be a non-negative duration")
+	}
+
 	return &Config{
+		Env:     appEnv,
 		AppPort: appPort,
+		HTTP: HTTPConfig{
+			ReadTimeout:       httpReadTimeout,
+			ReadHeaderTimeout: httpReadHeaderTimeout,
+			WriteTimeout:      httpWriteTimeout,
+			IdleTimeout:       httpIdleTimeout,
+			ShutdownTimeout:   httpShutdownTimeout,
+		},
 		Database: DatabaseConfig{
 			Host:     dbHost,
 			Port:     dbPort,
@@ -96,6 +574,19 @@ func Load() (*Config, error) {
 			Password: dbPassword,
 			DBName:   dbName,
 			SSLMode:  dbSSLMode,
+
+			MigrateOnStartup: dbMigrateOnStartup,
+
+			MaxOpenConns:     dbMaxOpenConns,
+			MaxIdleConns:     dbMaxIdleConns,
+			ConnMaxLifetime:  dbConnMaxLifetime,
+			ConnMaxIdleTime:  dbConnMaxIdleTime,
+			StatementTimeout: dbStatementTimeout,
+			ConnectTimeout:   dbConnectTimeout,
+
+			Replicas:             dbReplicas,
+			ReplicaCheckInterval: dbReplicaCheckInterval,
+			ReadYourWritesWindow: dbReadYourWritesWindow,
 		},
 		JWT: JWTConfig{
 			SecretKey:       jwtSecret,
@@ -111,13 +602,193 @@ func Load() (*Config, error) {
 			Region:          s3Region,
 			UsePathStyle:    s3UsePathStyle,
 		},
+		Storage: StorageConfig{
+			Backend:  storageBackend,
+			LocalDir: storageLocalDir,
+		},
+		Reconcile: ReconcileConfig{
+			Interval: reconcileInterval,
+			DryRun:   reconcileDryRun,
+			MinAge:   reconcileMinAge,
+		},
+		Quota: QuotaConfig{
+			Period:  quotaPeriod,
+			Default: quotaDefault,
+			Roles:   quotaRoles,
+		},
+		Retention: RetentionConfig{
+			DefaultDays:   retentionDays,
+			SweepInterval: retentionInterval,
+			KeepMetadata:  retentionKeepMetadata,
+			BatchSize:     retentionBatchSize,
+		},
+		Health: HealthConfig{
+			CheckTimeout: healthCheckTimeout,
+			CacheTTL:     healthCacheTTL,
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracingExporter,
+			ServiceName: r.get("OTEL_SERVICE_NAME", "auth_service"),
+			SampleRatio: tracingSampleRatio,
+		},
+		Secrets: SecretsConfig{
+			PollInterval: secretsPollInterval,
+		},
+		CORS: CORSConfig{
+			AllowOrigins:     corsAllowOrigins,
+			AllowMethods:     splitList(r.get("CORS_ALLOW_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS")),
+			AllowHeaders:     splitList(r.get("CORS_ALLOW_HEADERS", "Origin,Content-Type,Accept,Authorization,Idempotency-Key,X-Read-Your-Writes,X-Request-ID")),
+			ExposeHeaders:    splitList(r.get("CORS_EXPOSE_HEADERS", "X-Request-ID,Idempotent-Replayed,Retry-After,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy")),
+			AllowCredentials: corsAllowCredentials,
+			MaxAge:           corsMaxAge,
+		},
+		Security: SecurityConfig{
+			HSTSMaxAge:     securityHSTSMaxAge,
+			TrustedProxies: trustedProxies,
+		},
+		RateLimit: RateLimitConfig{
+			Backend:  rateLimitBackend,
+			RedisURL: rateLimitRedisURL,
+			Register: rateLimitRegister,
+			Login:    rateLimitLogin,
+			Upload:   rateLimitUpload,
+			Export:   rateLimitExport,
+			Default:  rateLimitDefault,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:         idempotencyTTL,
+			LockTimeout: idempotencyLockTimeout,
+		},
+		Email: EmailConfig{
+			VerificationURL: emailVerificationURL,
+			VerificationTTL: emailVerificationTTL,
+			Sender:          emailSender,
+			From:            emailFrom,
+			SMTPHost:        smtpHost,
+			SMTPPort:        smtpPort,
+			SMTPUsername:    smtpUsername,
+			SMTPPassword:    smtpPassword,
+		},
+		Erasure: ErasureConfig{
+			Interval: erasureInterval,
+		},
 	}, nil
 }
 
-// getEnv retrieves an environment variable or returns a default value.
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// parseRateLimit parses "<burst>/<period>", e.g. "20/1m". "0" or "off" disables the limit.
+func parseRateLimit(key, value string) (RateLimit, error) {
+	if value == "0" || value == "off" {
+		return RateLimit{}, nil
+	}
+	burstStr, periodStr, ok := strings.Cut(value, "/")
+	burst, err := strconv.Atoi(burstStr)
+	if !ok || err != nil || burst <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid %s: %q is not like 20/1m", key, value)
+	}
+	period, err := time.ParseDuration(periodStr)
+	if err != nil || period <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid %s: %q is not like 20/1m", key, value)
+	}
+	return RateLimit{Burst: burst, Period: period}, nil
+}
+
+// splitList splits a comma-separated setting, dropping blanks.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// validateOrigin accepts "*" or scheme://host[:port], where host may start
+// with "*." to match any subdomain.
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return fmt.Errorf("%q is not an origin like https://app.example.com or https://*.example.com", origin)
+	}
+	if strings.Contains(strings.Replace(origin, "://*.", "://", 1), "*") {
+		return fmt.Errorf("%q: only a leading \"*.\" subdomain wildcard is supported", origin)
+	}
+	return nil
+}
+
+// loadQuotaLimits reads <prefix>_MAX_BYTES, <prefix>_MAX_FILES and
+// <prefix>_MAX_UPLOADS_PER_PERIOD, using defaults for unset variables. 0 means unlimited.
+func loadQuotaLimits(r *resolver, prefix string, defaults models.QuotaLimits) (models.QuotaLimits, error) {
+	limits := defaults
+	if v, ok := r.lookup(prefix + "_MAX_BYTES"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return limits, fmt.Errorf("invalid %s_MAX_BYTES: %w", prefix, err)
+		}
+		limits.MaxBytes = n
+	}
+	if v, ok := r.lookup(prefix + "_MAX_FILES"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return limits, fmt.Errorf("invalid %s_MAX_FILES: %w", prefix, err)
+		}
+		limits.MaxFiles = n
+	}
+	if v, ok := r.lookup(prefix + "_MAX_UPLOADS_PER_PERIOD"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return limits, fmt.Errorf("invalid %s_MAX_UPLOADS_PER_PERIOD: %w", prefix, err)
+		}
+		limits.MaxUploadsPerPeriod = n
+	}
+	return limits, nil
+}
+
+// InsecureSettings lists defaults and weak secrets that are acceptable for
+// local development only. Load refuses to start with any of them in production.
+func (c *Config) InsecureSettings() []string {
+	var problems []string
+	switch {
+	case c.JWT.SecretKey == defaultJWTSecret:
+		problems = append(problems, "JWT_SECRET_KEY is the built-in default")
+	case len(c.JWT.SecretKey) < minJWTSecretLen:
+		problems = append(problems, fmt.Sprintf("JWT_SECRET_KEY is shorter than %d characters", minJWTSecretLen))
+	}
+	if c.Database.Password == "" || c.Database.Password == defaultDBPassword {
+		problems = append(problems, "DB_PASSWORD is empty or the built-in default")
+	}
+	if c.Storage.Backend == "s3" {
+		if c.S3.AccessKeyID == "" || c.S3.SecretAccessKey == "" {
+			problems = append(problems, "S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY must be set")
+		} else if c.S3.AccessKeyID == "minioadmin" && c.S3.SecretAccessKey == "minioadmin" {
+			problems = append(problems, "S3 credentials are the MinIO defaults")
+		}
+	}
+	if c.Email.Sender == "log" {
+		problems = append(problems, "EMAIL_SENDER is log, which writes emails to the log instead of delivering them")
+	}
+	return problems
+}
+
+// Settings returns every resolved setting with its source, in load order.
+func (c *Config) Settings() []Setting {
+	return c.settings
+}
+
+// Print writes the resolved settings as flat YAML, which Load accepts as a
+// config file, annotated with their source. With redact set, secrets are masked.
+func (c *Config) Print(w io.Writer, redact bool) error {
+	var errs []error
+	for _, s := range c.settings {
+		value := s.Value
+		if redact && secretKeys[s.Key] && value != "" {
+			value = "<redacted>"
+		}
+		_, err := fmt.Fprintf(w, "%s: %s # %s\n", s.Key, strconv.Quote(value), s.Source)
+		errs = append(errs, err)
 	}
-	return defaultValue
+	return errors.Join(errs...)
 }
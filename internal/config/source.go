@@ -0,0 +1,236 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sources of a setting, from lowest to highest precedence.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+
+	SourceSecretFile = "secret file" // Read from the path in <KEY>_FILE
+)
+
+// secretKeys are settings hidden by Config.Print when redacting. Each can
+// also be read from a file named by <KEY>_FILE.
+var secretKeys = map[string]bool{
+	"DB_PASSWORD":          true,
+	"JWT_SECRET_KEY":       true,
+	"S3_ACCESS_KEY_ID":     true,
+	"S3_SECRET_ACCESS_KEY": true,
+	"RATE_LIMIT_REDIS_URL": true, // May embed the Redis password
+	"SMTP_PASSWORD":        true,
+}
+
+// Options selects the sources Load reads besides the environment.
+type Options struct {
+	File      string            // YAML config file; empty for none
+	Overrides map[string]string // Settings given on the command line, keyed like the env vars
+
+	// AllowInsecure loads a production config despite InsecureSettings,
+	// so `config print` can show what the server would refuse.
+	AllowInsecure bool
+}
+
+// RegisterFlags adds -config FILE and repeatable -set KEY=VALUE to fs.
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.File, "config", os.Getenv("CONFIG_FILE"), "YAML config file (env: CONFIG_FILE)")
+	fs.Func("set", "override a setting, e.g. -set DB_HOST=db (repeatable)", func(s string) error {
+		key, value, ok := strings.Cut(s, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("want KEY=VALUE, got %q", s)
+		}
+		o.Set(key, value)
+		return nil
+	})
+}
+
+// Set adds a command-line override.
+func (o *Options) Set(key, value string) {
+	if o.Overrides == nil {
+		o.Overrides = make(map[string]string)
+	}
+	o.Overrides[strings.ToUpper(key)] = value
+}
+
+// Setting is one resolved configuration value and where it came from.
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// resolver looks settings up in the flag, env and file layers, in that
+// order, and records every key it was asked for.
+type resolver struct {
+	flags       map[string]string
+	file        map[string]string
+	used        map[string]bool
+	settings    []Setting
+	secretFiles map[string]string // Secret key -> file it was read from
+	errs        []error
+}
+
+func newResolver(opts Options) (*resolver, error) {
+	r := &resolver{flags: opts.Overrides, used: make(map[string]bool), secretFiles: make(map[string]string)}
+	if opts.File != "" {
+		data, err := os.ReadFile(opts.File)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %w", err)
+		}
+		if r.file, err = parseFile(data); err != nil {
+			return nil, fmt.Errorf("parse config file %s: %w", opts.File, err)
+		}
+	}
+	return r, nil
+}
+
+// lookup returns the highest-precedence value set for key.
+func (r *resolver) lookup(key string) (string, bool) {
+	value, source, ok := r.find(key)
+	if ok {
+		r.record(key, value, source)
+	}
+	return value, ok
+}
+
+// get returns the value for key, or defaultValue if no source sets it.
+func (r *resolver) get(key, defaultValue string) string {
+	value, source, ok := r.find(key)
+	if !ok {
+		value, source = defaultValue, SourceDefault
+	}
+	r.record(key, value, source)
+	return value
+}
+
+func (r *resolver) find(key string) (value, source string, ok bool) {
+	if secretKeys[key] {
+		if path, pathSource, ok := r.find(key + "_FILE"); ok {
+			r.record(key+"_FILE", path, pathSource)
+			if _, _, direct := r.findDirect(key); direct {
+				r.errs = append(r.errs, fmt.Errorf("both %s and %s_FILE are set", key, key))
+			}
+			value, err := ReadSecretFile(path)
+			if err != nil {
+				r.errs = append(r.errs, fmt.Errorf("invalid %s_FILE: %w", key, err))
+			}
+			r.secretFiles[key] = path
+			return value, SourceSecretFile, true
+		}
+	}
+	return r.findDirect(key)
+}
+
+func (r *resolver) findDirect(key string) (value, source string, ok bool) {
+	if v, ok := r.flags[key]; ok {
+		return v, SourceFlag, true
+	}
+	if v, ok := os.LookupEnv(key); ok {
+		return v, SourceEnv, true
+	}
+	if v, ok := r.file[key]; ok {
+		return v, SourceFile, true
+	}
+	return "", "", false
+}
+
+func (r *resolver) record(key, value, source string) {
+	if r.used[key] {
+		return
+	}
+	r.used[key] = true
+	r.settings = append(r.settings, Setting{Key: key, Value: value, Source: source})
+}
+
+// unknownKeys returns file and flag keys that no setting read, which are
+// almost always typos. Unknown env vars are not reported: the environment
+// holds plenty of unrelated variables.
+func (r *resolver) unknownKeys() []string {
+	var unknown []string
+	for _, layer := range []map[string]string{r.flags, r.file} {
+		for key := range layer {
+			if !r.used[key] {
+				unknown = append(unknown, key)
+			}
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// parseFile flattens a YAML document into env-style keys: nested keys are
+// joined with "_" and upper-cased, so
+//
+//	db:
+//	  host: postgres
+//	  replicas: [replica-1, replica-2]
+//
+// sets DB_HOST=postgres and DB_REPLICAS=replica-1,replica-2.
+func parseFile(data []byte) (map[string]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	if len(doc.Content) == 0 {
+		return values, nil // Empty file
+	}
+	if err := flatten(doc.Content[0], "", values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flatten(node *yaml.Node, prefix string, values map[string]string) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := strings.ToUpper(node.Content[i].Value)
+			if prefix != "" {
+				key = prefix + "_" + key
+			}
+			if err := flatten(node.Content[i+1], key, values); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: %s: lists may only hold plain values", item.Line, prefix)
+			}
+			items = append(items, item.Value)
+		}
+		values[prefix] = strings.Join(items, ",")
+	case yaml.ScalarNode:
+		if prefix == "" {
+			return fmt.Errorf("line %d: expected a mapping at the top level", node.Line)
+		}
+		values[prefix] = node.Value
+	case yaml.AliasNode:
+		return flatten(node.Alias, prefix, values)
+	default:
+		return fmt.Errorf("line %d: unsupported YAML node", node.Line)
+	}
+	return nil
+}
+
+// ReadSecretFile reads a secret mounted as a file, trimming the trailing
+// newline most tools write.
+func ReadSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
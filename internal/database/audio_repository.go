@@ -5,23 +5,27 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"example.com/auth_service/internal/models"
 	"example.com/auth_service/pkg/logger"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 	// "example.com/auth_service/pkg/logger"
 )
 
+// audioFileColumns lists the audio_files columns in models.AudioFile order.
+const audioFileColumns = `id, user_id, s3_key, original_filename, content_type, size_bytes,
+	COALESCE(content_sha256, '') AS content_sha256, uploaded_at, purged_at`
+
 // audioRepositoryImpl implements the models.AudioRepository interface.
 type audioRepositoryImpl struct {
-	db     *sqlx.DB
+	db     *Cluster
 	logger *logger.Logger
 }
 
 // NewAudioRepository creates a new instance that implements models.AudioRepository.
-func NewAudioRepository(db *sqlx.DB, appLogger *logger.Logger) models.AudioRepository {
+func NewAudioRepository(db *Cluster, appLogger *logger.Logger) models.AudioRepository {
 	return &audioRepositoryImpl{
 		db:     db,
 		logger: appLogger,
@@ -30,54 +34,287 @@ func NewAudioRepository(db *sqlx.DB, appLogger *logger.Logger) models.AudioRepos
 
 // SaveAudioFile saves audio file metadata to the database.
 func (r *audioRepositoryImpl) SaveAudioFile(ctx context.Context, audioFile *models.AudioFile) error {
-	query := `INSERT INTO audio_files (id, user_id, s3_key, original_filename, content_type, size_bytes, uploaded_at)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	query := `INSERT INTO audio_files (id, user_id, s3_key, original_filename, content_type, size_bytes, content_sha256, uploaded_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := r.db.writer(ctx).ExecContext(ctx, query,
 		audioFile.ID,
 		audioFile.UserID,
 		audioFile.S3Key,
 		audioFile.OriginalFilename,
 		audioFile.ContentType,
 		audioFile.SizeBytes,
+		audioFile.ContentSHA256,
 		audioFile.UploadedAt,
 	)
 
 	if err != nil {
-		r.logger.Error("Error saving audio file metadata to DB", zap.Error(err), zap.String("s3_key", audioFile.S3Key))
+		r.logger.FromContext(ctx).Error("Error saving audio file metadata to DB", zap.Error(err), zap.String("s3_key", audioFile.S3Key))
 		return fmt.Errorf("SaveAudioFile: failed to insert audio metadata: %w", err)
 	}
-	r.logger.Info("Audio file metadata saved to DB", zap.String("id", audioFile.ID.String()), zap.String("s3_key", audioFile.S3Key))
+	r.logger.FromContext(ctx).Info("Audio file metadata saved to DB", zap.String("id", audioFile.ID.String()), zap.String("s3_key", audioFile.S3Key))
 	return nil
 }
 
 // GetAudioFileByID retrieves audio file metadata from the database by its ID.
 func (r *audioRepositoryImpl) GetAudioFileByID(ctx context.Context, id uuid.UUID) (*models.AudioFile, error) {
 	var audioFile models.AudioFile
-	query := `SELECT id, user_id, s3_key, original_filename, content_type, size_bytes, uploaded_at 
-			  FROM audio_files WHERE id = $1`
+	query := `SELECT ` + audioFileColumns + ` FROM audio_files WHERE id = $1`
 
-	err := r.db.GetContext(ctx, &audioFile, query, id)
+	err := r.db.reader(ctx).GetContext(ctx, &audioFile, query, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			r.logger.Debug("Audio file metadata not found by ID", zap.String("id", id.String()))
+			r.logger.FromContext(ctx).Debug("Audio file metadata not found by ID", zap.String("id", id.String()))
 			return nil, err // Return sql.ErrNoRows directly
 		}
-		r.logger.Error("Error fetching audio file metadata by ID from DB", zap.Error(err), zap.String("id", id.String()))
+		r.logger.FromContext(ctx).Error("Error fetching audio file metadata by ID from DB", zap.Error(err), zap.String("id", id.String()))
 		return nil, fmt.Errorf("GetAudioFileByID: query error: %w", err)
 	}
-	r.logger.Debug("Audio file metadata found by ID", zap.String("id", id.String()))
+	r.logger.FromContext(ctx).Debug("Audio file metadata found by ID", zap.String("id", id.String()))
 	return &audioFile, nil
 }
 
-// Optional: Implement ListAudioFilesByUserID if needed
-// func (r *audioRepositoryImpl) ListAudioFilesByUserID(ctx context.Context, userID uuid.UUID) ([]models.AudioFile, error) {
-// 	var files []models.AudioFile
-// 	query := `SELECT id, user_id, s3_key, original_filename, content_type, size_bytes, uploaded_at
-// 			  FROM audio_files WHERE user_id = $1 ORDER BY uploaded_at DESC`
-// 	err := r.db.SelectContext(ctx, &files, query, userID)
-// 	if err != nil {
-// 		return nil, fmt.Errorf("ListAudioFilesByUserID: query error: %w", err)
-// 	}
-// 	return files, nil
-// }
+// ListAudioFilesAfterKey returns up to limit audio files whose (s3_key, id) sorts after (afterKey, afterID).
+// Keys are compared byte-wise (COLLATE "C") so the order matches S3 ListObjectsV2.
+// The id breaks ties between deduplicated rows sharing a key, so a page
+// boundary inside such a group does not skip the rest of it.
+func (r *audioRepositoryImpl) ListAudioFilesAfterKey(ctx context.Context, afterKey string, afterID uuid.UUID, limit int) ([]models.AudioFile, error) {
+	var files []models.AudioFile
+	query := `SELECT ` + audioFileColumns + ` FROM audio_files
+			  WHERE purged_at IS NULL AND (s3_key COLLATE "C", id) > ($1, $2)
+			  ORDER BY s3_key COLLATE "C", id LIMIT $3`
+	if err := r.db.writer(ctx).SelectContext(ctx, &files, query, afterKey, afterID, limit); err != nil {
+		r.logger.FromContext(ctx).Error("Error listing audio files by key from DB", zap.Error(err), zap.String("after_key", afterKey), zap.String("after_id", afterID.String()))
+		return nil, fmt.Errorf("ListAudioFilesAfterKey: query error: %w", err)
+	}
+	return files, nil
+}
+
+// ListAudioFilesByUserID returns every audio file of a user, including purged
+// ones, oldest first.
+func (r *audioRepositoryImpl) ListAudioFilesByUserID(ctx context.Context, userID uuid.UUID) ([]models.AudioFile, error) {
+	var files []models.AudioFile
+	query := `SELECT ` + audioFileColumns + ` FROM audio_files WHERE user_id = $1 ORDER BY uploaded_at, id`
+	if err := r.db.reader(ctx).SelectContext(ctx, &files, query, userID); err != nil {
+		r.logger.FromContext(ctx).Error("Error listing audio files by user from DB", zap.Error(err), zap.String("userID", userID.String()))
+		return nil, fmt.Errorf("ListAudioFilesByUserID: query error: %w", err)
+	}
+	return files, nil
+}
+
+// DeleteAudioFile removes audio file metadata from the database by its ID,
+// releasing its blob reference in the same transaction.
+// Returns sql.ErrNoRows if no row was deleted.
+func (r *audioRepositoryImpl) DeleteAudioFile(ctx context.Context, id uuid.UUID, onLastRef func(s3Key string) error) error {
+	err := withinTx(ctx, r.db.Primary, func(ctx context.Context) error {
+		var deleted struct {
+			S3Key         string         `db:"s3_key"`
+			ContentSHA256 sql.NullString `db:"content_sha256"`
+		}
+		err := r.db.writer(ctx).GetContext(ctx, &deleted, `DELETE FROM audio_files WHERE id = $1 RETURNING s3_key, content_sha256`, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			r.logger.FromContext(ctx).Error("Error deleting audio file metadata from DB", zap.Error(err), zap.String("id", id.String()))
+			return fmt.Errorf("failed to delete audio metadata: %w", err)
+		}
+
+		if deleted.ContentSHA256.Valid {
+			return r.releaseBlob(ctx, deleted.ContentSHA256.String, onLastRef)
+		}
+		// Files stored before deduplication own their object outright.
+		return onLastRef(deleted.S3Key)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		return fmt.Errorf("DeleteAudioFile: %w", err)
+	}
+	r.logger.FromContext(ctx).Info("Audio file metadata deleted from DB", zap.String("id", id.String()))
+	return nil
+}
+
+// ListExpiredAudioFiles returns files past their owner's retention period, oldest first.
+func (r *audioRepositoryImpl) ListExpiredAudioFiles(ctx context.Context, defaultDays int, limit int) ([]models.AudioFile, error) {
+	var files []models.AudioFile
+	query := `SELECT af.id, af.user_id, af.s3_key, af.original_filename, af.content_type, af.size_bytes,
+				COALESCE(af.content_sha256, '') AS content_sha256, af.uploaded_at, af.purged_at
+			  FROM audio_files af JOIN users u ON u.id = af.user_id
+			  WHERE af.purged_at IS NULL
+				AND COALESCE(u.retention_days, $1) > 0
+				AND af.uploaded_at < NOW() - COALESCE(u.retention_days, $1) * INTERVAL '1 day'
+			  ORDER BY af.uploaded_at LIMIT $2`
+	if err := r.db.writer(ctx).SelectContext(ctx, &files, query, defaultDays, limit); err != nil {
+		r.logger.FromContext(ctx).Error("Error listing expired audio files from DB", zap.Error(err))
+		return nil, fmt.Errorf("ListExpiredAudioFiles: query error: %w", err)
+	}
+	return files, nil
+}
+
+// PurgeAudioFile deletes or anonymises the row, releases its blob reference
+// and writes the audit record, all in one transaction.
+func (r *audioRepositoryImpl) PurgeAudioFile(ctx context.Context, audioFile *models.AudioFile, reason string, keepMetadata bool, onLastRef func(s3Key string) error) error {
+	err := withinTx(ctx, r.db.Primary, func(ctx context.Context) error {
+		db := r.db.writer(ctx)
+
+		var res sql.Result
+		var err error
+		if keepMetadata {
+			res, err = db.ExecContext(ctx, `UPDATE audio_files
+				SET s3_key = '', original_filename = 'purged', content_sha256 = NULL, purged_at = NOW()
+				WHERE id = $1 AND purged_at IS NULL`, audioFile.ID)
+		} else {
+			res, err = db.ExecContext(ctx, `DELETE FROM audio_files WHERE id = $1 AND purged_at IS NULL`, audioFile.ID)
+		}
+		if err != nil {
+			r.logger.FromContext(ctx).Error("Error purging audio file metadata", zap.Error(err), zap.String("id", audioFile.ID.String()))
+			return fmt.Errorf("failed to purge audio metadata: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return sql.ErrNoRows // Deleted or purged concurrently
+		}
+
+		// onLastRef is called last, after the audit record, since it deletes the object
+		lastRefKey := audioFile.S3Key // Files stored before deduplication own their object
+		if audioFile.ContentSHA256 != "" {
+			lastRefKey = ""
+			err = r.releaseBlob(ctx, audioFile.ContentSHA256, func(s3Key string) error {
+				lastRefKey = s3Key
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = db.ExecContext(ctx, `INSERT INTO audio_purges
+				(id, audio_file_id, user_id, s3_key, content_sha256, size_bytes, uploaded_at, reason, metadata_kept)
+				VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9)`,
+			uuid.New(), audioFile.ID, audioFile.UserID, audioFile.S3Key, audioFile.ContentSHA256,
+			audioFile.SizeBytes, audioFile.UploadedAt, reason, keepMetadata)
+		if err != nil {
+			return fmt.Errorf("failed to record purge: %w", err)
+		}
+		if lastRefKey == "" {
+			return nil
+		}
+		return onLastRef(lastRefKey)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		return fmt.Errorf("PurgeAudioFile: %w", err)
+	}
+	r.logger.FromContext(ctx).Info("Audio file purged",
+		zap.String("id", audioFile.ID.String()),
+		zap.String("reason", reason),
+		zap.Bool("metadata_kept", keepMetadata))
+	return nil
+}
+
+// AcquireBlob inserts the blob row with one reference, or increments the
+// reference count if the content is already known.
+func (r *audioRepositoryImpl) AcquireBlob(ctx context.Context, blob *models.AudioBlob) error {
+	query := `INSERT INTO audio_blobs (content_sha256, s3_key, size_bytes, content_type, ref_count)
+			  VALUES ($1, $2, $3, $4, 1)
+			  ON CONFLICT (content_sha256) DO UPDATE SET ref_count = audio_blobs.ref_count + 1, acquired_at = NOW()
+			  RETURNING ref_count, created_at`
+	err := r.db.writer(ctx).QueryRowxContext(ctx, query, blob.ContentSHA256, blob.S3Key, blob.SizeBytes, blob.ContentType).
+		Scan(&blob.RefCount, &blob.CreatedAt)
+	if err != nil {
+		r.logger.FromContext(ctx).Error("Error acquiring audio blob reference", zap.Error(err), zap.String("sha256", blob.ContentSHA256))
+		return fmt.Errorf("AcquireBlob: %w", err)
+	}
+	r.logger.FromContext(ctx).Debug("Audio blob reference acquired", zap.String("sha256", blob.ContentSHA256), zap.Int("ref_count", blob.RefCount))
+	return nil
+}
+
+// ReleaseBlob drops one reference to the blob in a transaction.
+func (r *audioRepositoryImpl) ReleaseBlob(ctx context.Context, contentSHA256 string, onLastRef func(s3Key string) error) error {
+	err := withinTx(ctx, r.db.Primary, func(ctx context.Context) error {
+		return r.releaseBlob(ctx, contentSHA256, onLastRef)
+	})
+	if err != nil {
+		return fmt.Errorf("ReleaseBlob: %w", err)
+	}
+	return nil
+}
+
+// releaseBlob decrements the reference count; ctx must carry a transaction.
+// The blob row stays locked until it ends, so a concurrent AcquireBlob waits
+// until the object has been deleted and then recreates both row and object.
+func (r *audioRepositoryImpl) releaseBlob(ctx context.Context, contentSHA256 string, onLastRef func(s3Key string) error) error {
+	db := r.db.writer(ctx)
+	var blob models.AudioBlob
+	query := `UPDATE audio_blobs SET ref_count = ref_count - 1 WHERE content_sha256 = $1
+			  RETURNING content_sha256, s3_key, size_bytes, content_type, ref_count, created_at`
+	if err := db.GetContext(ctx, &blob, query, contentSHA256); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.logger.FromContext(ctx).Warn("Releasing unknown audio blob", zap.String("sha256", contentSHA256))
+			return nil
+		}
+		return fmt.Errorf("failed to release blob %s: %w", contentSHA256, err)
+	}
+	if blob.RefCount > 0 {
+		return nil
+	}
+
+	if _, err := db.ExecContext(ctx, `DELETE FROM audio_blobs WHERE content_sha256 = $1`, contentSHA256); err != nil {
+		return fmt.Errorf("failed to delete blob row %s: %w", contentSHA256, err)
+	}
+	if err := onLastRef(blob.S3Key); err != nil {
+		return err
+	}
+	r.logger.FromContext(ctx).Info("Last reference to audio blob released", zap.String("sha256", contentSHA256), zap.String("s3_key", blob.S3Key))
+	return nil
+}
+
+// DeleteUnreferencedBlob deletes the blob row for s3Key in a transaction, if
+// it exists and nothing uses it. The row is locked first, so a concurrent
+// AcquireBlob waits and then recreates both row and object.
+func (r *audioRepositoryImpl) DeleteUnreferencedBlob(ctx context.Context, s3Key string, acquiredBefore time.Time, onDelete func() error) (bool, error) {
+	deleted := false
+	err := withinTx(ctx, r.db.Primary, func(ctx context.Context) error {
+		db := r.db.writer(ctx)
+		var blob struct {
+			ContentSHA256 string    `db:"content_sha256"`
+			RefCount      int       `db:"ref_count"`
+			AcquiredAt    time.Time `db:"acquired_at"`
+			InUse         bool      `db:"in_use"`
+		}
+		err := db.GetContext(ctx, &blob,
+			`SELECT content_sha256, ref_count, acquired_at,
+					EXISTS (SELECT 1 FROM audio_files f WHERE f.content_sha256 = b.content_sha256) AS in_use
+			   FROM audio_blobs b WHERE s3_key = $1 FOR UPDATE`, s3Key)
+		if errors.Is(err, sql.ErrNoRows) {
+			return onDelete()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock blob %s: %w", s3Key, err)
+		}
+		if blob.InUse || blob.AcquiredAt.After(acquiredBefore) {
+			return models.ErrBlobInUse
+		}
+
+		if _, err := db.ExecContext(ctx, `DELETE FROM audio_blobs WHERE content_sha256 = $1`, blob.ContentSHA256); err != nil {
+			return fmt.Errorf("failed to delete blob row %s: %w", blob.ContentSHA256, err)
+		}
+		if err := onDelete(); err != nil {
+			return err
+		}
+		deleted = true
+		r.logger.FromContext(ctx).Warn("Deleted audio blob with leaked references",
+			zap.String("sha256", blob.ContentSHA256), zap.String("s3_key", s3Key), zap.Int("ref_count", blob.RefCount))
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrBlobInUse) {
+			return false, err
+		}
+		return false, fmt.Errorf("DeleteUnreferencedBlob: %w", err)
+	}
+	return deleted, nil
+}
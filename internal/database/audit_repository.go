@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// auditEntryColumns lists the admin_audit_log columns in models.AuditEntry order.
+const auditEntryColumns = `id, actor_id, action, target_user_id, status, request_id, details, created_at`
+
+// auditRepositoryImpl implements the models.AuditRepository interface.
+type auditRepositoryImpl struct {
+	db     *Cluster
+	logger *logger.Logger
+}
+
+// NewAuditRepository creates a new instance that implements models.AuditRepository.
+func NewAuditRepository(db *Cluster, appLogger *logger.Logger) models.AuditRepository {
+	return &auditRepositoryImpl{
+		db:     db,
+		logger: appLogger,
+	}
+}
+
+// RecordAudit inserts entry. created_at is set by the database.
+func (r *auditRepositoryImpl) RecordAudit(ctx context.Context, entry *models.AuditEntry) error {
+	details := entry.Details
+	if len(details) == 0 {
+		details = []byte(`{}`)
+	}
+	query := `INSERT INTO admin_audit_log (id, actor_id, action, target_user_id, status, request_id, details)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.db.writer(ctx).ExecContext(ctx, query,
+		entry.ID, entry.ActorID, entry.Action, entry.TargetUserID, entry.Status, entry.RequestID, details)
+	if err != nil {
+		r.logger.FromContext(ctx).Error("Error recording admin audit entry", zap.Error(err),
+			zap.String("actor_id", entry.ActorID.String()), zap.String("action", entry.Action))
+		return fmt.Errorf("RecordAudit: insert error: %w", err)
+	}
+	return nil
+}
+
+// ListAudit returns a page of entries matching filter, newest first, and the
+// total number of matches. It reads from the primary: an admin checking what
+// was just done expects to see it.
+func (r *auditRepositoryImpl) ListAudit(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]models.AuditEntry, int, error) {
+	var conds []string
+	var args []any
+	if filter.ActorID != nil {
+		args = append(args, *filter.ActorID)
+		conds = append(conds, fmt.Sprintf(`actor_id = $%d`, len(args)))
+	}
+	if filter.TargetUserID != nil {
+		args = append(args, *filter.TargetUserID)
+		conds = append(conds, fmt.Sprintf(`target_user_id = $%d`, len(args)))
+	}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		conds = append(conds, fmt.Sprintf(`action = $%d`, len(args)))
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = ` WHERE ` + strings.Join(conds, ` AND `)
+	}
+
+	db := r.db.writer(ctx)
+	var total int
+	if err := db.GetContext(ctx, &total, `SELECT COUNT(*) FROM admin_audit_log`+where, args...); err != nil {
+		r.logger.FromContext(ctx).Error("Error counting admin audit entries", zap.Error(err))
+		return nil, 0, fmt.Errorf("ListAudit: count error: %w", err)
+	}
+
+	entries := []models.AuditEntry{}
+	query := `SELECT ` + auditEntryColumns + ` FROM admin_audit_log` + where +
+		fmt.Sprintf(` ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+	if err := db.SelectContext(ctx, &entries, query, append(args, limit, offset)...); err != nil {
+		r.logger.FromContext(ctx).Error("Error listing admin audit entries", zap.Error(err))
+		return nil, 0, fmt.Errorf("ListAudit: query error: %w", err)
+	}
+	return entries, total, nil
+}
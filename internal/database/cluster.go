@@ -0,0 +1,186 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// replicaPingTimeout bounds each replica health check.
+const replicaPingTimeout = 2 * time.Second
+
+// primaryKey marks a context whose reads must go to the primary.
+type primaryKey struct{}
+
+// WithPrimary returns a context whose repository reads go to the primary.
+// Use it right after a mutation, when a replica may not have caught up yet.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// replica is one read replica and the result of its latest health check.
+type replica struct {
+	addr    string
+	db      *sqlx.DB
+	healthy atomic.Bool
+}
+
+// Cluster is the primary database plus zero or more read replicas.
+// Writes and transactions always use the primary. Reads go to a healthy
+// replica, round-robin, and fall back to the primary when none is healthy.
+type Cluster struct {
+	Primary    *sqlx.DB
+	replicas   []*replica
+	connectors []*connector // Primary and replicas, for SetPassword
+	next       atomic.Uint32
+	logger     *logger.Logger
+}
+
+// NewCluster wraps a single database with no replicas.
+func NewCluster(primary *sqlx.DB, appLogger *logger.Logger) *Cluster {
+	return &Cluster{Primary: primary, logger: appLogger}
+}
+
+// ConnectCluster connects to the primary (see Connect) and opens a pool for
+// each of cfg.Replicas. Replicas that are down at startup are marked unhealthy
+// rather than failing startup; MonitorReplicas brings them back.
+func ConnectCluster(ctx context.Context, cfg config.DatabaseConfig, appLogger *logger.Logger) (*Cluster, error) {
+	primary, conn, err := connect(ctx, cfg, appLogger)
+	if err != nil {
+		return nil, err
+	}
+	c := NewCluster(primary, appLogger)
+	c.connectors = append(c.connectors, conn)
+
+	for _, addr := range cfg.Replicas {
+		replicaCfg := cfg
+		replicaCfg.Host, replicaCfg.Port = splitHostPort(addr, cfg.Port)
+		db, conn, err := open(replicaCfg)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to open replica %s: %w", addr, err)
+		}
+		c.replicas = append(c.replicas, &replica{addr: addr, db: db})
+		c.connectors = append(c.connectors, conn)
+	}
+	c.checkReplicas(ctx)
+	return c, nil
+}
+
+// Close closes the primary and every replica pool.
+func (c *Cluster) Close() error {
+	err := c.Primary.Close()
+	for _, r := range c.replicas {
+		if rerr := r.db.Close(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// SetPassword changes the password used for new connections to the primary
+// and every replica. Open connections stay as they are.
+func (c *Cluster) SetPassword(password string) error {
+	for _, conn := range c.connectors {
+		if err := conn.setPassword(password); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReplicaHealth returns the latest health check result for each replica, keyed by address.
+func (c *Cluster) ReplicaHealth() map[string]bool {
+	health := make(map[string]bool, len(c.replicas))
+	for _, r := range c.replicas {
+		health[r.addr] = r.healthy.Load()
+	}
+	return health
+}
+
+// ReplicaPools returns the connection pool of each replica, keyed by address.
+func (c *Cluster) ReplicaPools() map[string]*sqlx.DB {
+	pools := make(map[string]*sqlx.DB, len(c.replicas))
+	for _, r := range c.replicas {
+		pools[r.addr] = r.db
+	}
+	return pools
+}
+
+// MonitorReplicas re-checks replica health every interval until ctx is cancelled.
+func (c *Cluster) MonitorReplicas(ctx context.Context, interval time.Duration) {
+	if len(c.replicas) == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.checkReplicas(ctx)
+		}
+	}
+}
+
+// checkReplicas pings every replica and logs health transitions.
+func (c *Cluster) checkReplicas(ctx context.Context) {
+	for _, r := range c.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+		err := r.db.PingContext(pingCtx)
+		cancel()
+
+		healthy := err == nil
+		if r.healthy.Swap(healthy) != healthy {
+			if healthy {
+				c.logger.Info("Database replica healthy", zap.String("replica", r.addr))
+			} else {
+				c.logger.Warn("Database replica unhealthy, reading from primary", zap.String("replica", r.addr), zap.Error(err))
+			}
+		}
+	}
+}
+
+// writer returns the executor for writes: the transaction carried by ctx, or the primary.
+func (c *Cluster) writer(ctx context.Context) dbtx {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return instrumentedExecutor{next: tx, target: targetTx}
+	}
+	return instrumentedExecutor{next: c.Primary, target: targetPrimary}
+}
+
+// reader returns the executor for reads. Reads inside a transaction or on a
+// WithPrimary context use the primary; otherwise the next healthy replica is used.
+func (c *Cluster) reader(ctx context.Context) dbtx {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return instrumentedExecutor{next: tx, target: targetTx}
+	}
+	if len(c.replicas) == 0 || ctx.Value(primaryKey{}) != nil {
+		return instrumentedExecutor{next: c.Primary, target: targetPrimary}
+	}
+	start := c.next.Add(1)
+	for i := range c.replicas {
+		r := c.replicas[(int(start)+i)%len(c.replicas)]
+		if r.healthy.Load() {
+			return instrumentedExecutor{next: r.db, target: targetReplica}
+		}
+	}
+	return instrumentedExecutor{next: c.Primary, target: targetPrimary}
+}
+
+// splitHostPort splits "host:port", using defaultPort when addr has no port.
+func splitHostPort(addr, defaultPort string) (string, string) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, defaultPort
+	}
+	return host, port
+}
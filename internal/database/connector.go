@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync/atomic"
+
+	"example.com/auth_service/internal/config"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// connector opens lib/pq connections with a password that can be replaced
+// at runtime. Only new connections use the new password; Postgres keeps
+// existing sessions open, so the pool turns over without errors.
+type connector struct {
+	cfg     config.DatabaseConfig
+	current atomic.Pointer[pq.Connector]
+}
+
+var _ driver.Connector = (*connector)(nil)
+
+func newConnector(cfg config.DatabaseConfig) (*connector, error) {
+	c := &connector{cfg: cfg}
+	if err := c.setPassword(cfg.Password); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *connector) setPassword(password string) error {
+	cfg := c.cfg
+	cfg.Password = password
+	pc, err := pq.NewConnector(dsn(cfg))
+	if err != nil {
+		return fmt.Errorf("invalid connection settings: %w", err)
+	}
+	c.current.Store(pc)
+	return nil
+}
+
+// Connect implements driver.Connector.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.current.Load().Connect(ctx)
+}
+
+// Driver implements driver.Connector.
+func (c *connector) Driver() driver.Driver {
+	return c.current.Load().Driver()
+}
+
+// open creates a pool for cfg without connecting, sized by cfg.
+func open(cfg config.DatabaseConfig) (*sqlx.DB, *connector, error) {
+	conn, err := newConnector(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	db := sqlx.NewDb(sql.OpenDB(conn), "postgres")
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	return db, conn, nil
+}
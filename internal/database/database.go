@@ -1,34 +1,86 @@
 package database
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq" // PostgreSQL driver
+	"go.uber.org/zap"
 
 	"example.com/auth_service/internal/config" // Import the config package
-	// "example.com/auth_service/internal/models"
+	"example.com/auth_service/pkg/logger"
 )
 
-// Connect establishes a connection to the PostgreSQL database.
-// It now uses config.DatabaseConfig from the central configuration.
-func Connect(cfg config.DatabaseConfig) (*sqlx.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
+// Backoff between connection attempts while Postgres is starting.
+const (
+	connectInitialBackoff = 500 * time.Millisecond
+	connectMaxBackoff     = 10 * time.Second
+)
+
+// Connect establishes a connection to the PostgreSQL database and configures the pool.
+// It retries with exponential backoff for up to cfg.ConnectTimeout, so the
+// server can start before Postgres is ready to accept connections.
+func Connect(ctx context.Context, cfg config.DatabaseConfig, appLogger *logger.Logger) (*sqlx.DB, error) {
+	db, _, err := connect(ctx, cfg, appLogger)
+	return db, err
+}
 
-	db, err := sqlx.Connect("postgres", dsn)
+func connect(ctx context.Context, cfg config.DatabaseConfig, appLogger *logger.Logger) (*sqlx.DB, *connector, error) {
+	db, conn, err := open(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+		return nil, nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err = db.Ping(); err != nil {
-		db.Close() // Close the connection if ping fails
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	deadline := time.Now().Add(cfg.ConnectTimeout)
+	backoff := connectInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil {
+			break
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			db.Close() // Close the pool if Postgres never became reachable
+			return nil, nil, fmt.Errorf("failed to ping database after %d attempts: %w", attempt, err)
+		}
+		appLogger.Warn("Database not ready, retrying",
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, nil, fmt.Errorf("failed to connect to database: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, connectMaxBackoff)
+	}
+
+	appLogger.Info("Successfully connected to the database",
+		zap.String("host", cfg.Host),
+		zap.String("dbname", cfg.DBName),
+		zap.Int("max_open_conns", cfg.MaxOpenConns),
+		zap.Int("max_idle_conns", cfg.MaxIdleConns),
+		zap.Duration("statement_timeout", cfg.StatementTimeout))
+	return db, conn, nil
+}
+
+// dsn builds the lib/pq connection string. lib/pq sends unrecognised keys such as
+// statement_timeout to the server as session parameters.
+func dsn(cfg config.DatabaseConfig) string {
+	s := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.User, quoteDSNValue(cfg.Password), cfg.DBName, cfg.SSLMode)
+	if cfg.StatementTimeout > 0 {
+		s += fmt.Sprintf(" statement_timeout=%d", cfg.StatementTimeout.Milliseconds())
 	}
+	return s
+}
 
-	log.Println("Successfully connected to the database!")
-	return db, nil
+// quoteDSNValue quotes a value for the key=value DSN format, so generated
+// passwords may contain spaces, quotes and backslashes.
+func quoteDSNValue(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
 }
 
 // --- UserRepository (Example, to be expanded) ---
@@ -58,13 +110,3 @@ func Connect(cfg config.DatabaseConfig) (*sqlx.DB, error) {
 // 	}
 // 	return &user, nil
 // }
-
-// func (r *UserRepository) GetUserByID(id string) (*models.User, error) {
-// 	var user models.User
-// 	query := `SELECT id, username, email, password_hash, created_at, updated_at FROM users WHERE id = $1`
-// 	err := r.db.Get(&user, query, id)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	return &user, nil
-// }
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/pkg/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// accountDeletionColumns lists the account_deletions columns in models.AccountDeletion order.
+const accountDeletionColumns = `user_id, requested_at, started_at, completed_at, attempts, last_error, files_deleted, objects_deleted`
+
+// erasureRepositoryImpl implements the models.ErasureRepository interface.
+type erasureRepositoryImpl struct {
+	db     *Cluster
+	logger *logger.Logger
+}
+
+// NewErasureRepository creates a new instance that implements models.ErasureRepository.
+func NewErasureRepository(db *Cluster, appLogger *logger.Logger) models.ErasureRepository {
+	return &erasureRepositoryImpl{
+		db:     db,
+		logger: appLogger,
+	}
+}
+
+// RequestErasure sets users.deleted_at and inserts the job in one statement.
+// Returns sql.ErrNoRows if the user does not exist.
+func (r *erasureRepositoryImpl) RequestErasure(ctx context.Context, userID uuid.UUID) (*models.AccountDeletion, error) {
+	var job models.AccountDeletion
+	query := `WITH marked AS (
+				UPDATE users SET deleted_at = COALESCE(deleted_at, NOW()) WHERE id = $1 RETURNING id
+			  )
+			  INSERT INTO account_deletions (user_id) SELECT id FROM marked
+			  ON CONFLICT (user_id) DO UPDATE SET user_id = EXCLUDED.user_id
+			  RETURNING ` + accountDeletionColumns
+	err := r.db.writer(ctx).GetContext(ctx, &job, query, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		r.logger.FromContext(ctx).Error("Error requesting account erasure", zap.Error(err), zap.String("userID", userID.String()))
+		return nil, fmt.Errorf("RequestErasure: query error: %w", err)
+	}
+	r.logger.FromContext(ctx).Info("Account erasure requested", zap.String("userID", userID.String()))
+	return &job, nil
+}
+
+// ClaimErasure marks the oldest claimable job as started. SKIP LOCKED lets
+// several instances claim different jobs at once.
+func (r *erasureRepositoryImpl) ClaimErasure(ctx context.Context, lease time.Duration) (*models.AccountDeletion, error) {
+	var job models.AccountDeletion
+	query := `UPDATE account_deletions SET started_at = NOW(), attempts = attempts + 1
+			  WHERE user_id = (
+				SELECT user_id FROM account_deletions
+				WHERE completed_at IS NULL
+				  AND (started_at IS NULL OR started_at + $1::double precision * INTERVAL '1 second' <= NOW())
+				ORDER BY requested_at LIMIT 1
+				FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + accountDeletionColumns
+	err := r.db.writer(ctx).GetContext(ctx, &job, query, lease.Seconds())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.FromContext(ctx).Error("Error claiming account erasure job", zap.Error(err))
+		return nil, fmt.Errorf("ClaimErasure: query error: %w", err)
+	}
+	return &job, nil
+}
+
+// CompleteErasure deletes the users row, which cascades to the user's other
+// tables, and the audio_purges audit rows, which have no foreign key.
+func (r *erasureRepositoryImpl) CompleteErasure(ctx context.Context, userID uuid.UUID, filesDeleted, objectsDeleted int) error {
+	err := withinTx(ctx, r.db.Primary, func(ctx context.Context) error {
+		db := r.db.writer(ctx)
+		if _, err := db.ExecContext(ctx, `DELETE FROM audio_purges WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete audio purge records: %w", err)
+		}
+		if _, err := db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		_, err := db.ExecContext(ctx, `UPDATE account_deletions
+				SET completed_at = NOW(), started_at = NULL, last_error = NULL, files_deleted = $2, objects_deleted = $3
+				WHERE user_id = $1`, userID, filesDeleted, objectsDeleted)
+		if err != nil {
+			return fmt.Errorf("failed to mark erasure complete: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		r.logger.FromContext(ctx).Error("Error completing account erasure", zap.Error(err), zap.String("userID", userID.String()))
+		return fmt.Errorf("CompleteErasure: %w", err)
+	}
+	r.logger.FromContext(ctx).Info("Account erased", zap.String("userID", userID.String()))
+	return nil
+}
+
+// FailErasure records the error. started_at is left alone, so the job is
+// retried once its lease expires rather than straight away.
+func (r *erasureRepositoryImpl) FailErasure(ctx context.Context, userID uuid.UUID, cause string) error {
+	_, err := r.db.writer(ctx).ExecContext(ctx,
+		`UPDATE account_deletions SET last_error = $2 WHERE user_id = $1`, userID, cause)
+	if err != nil {
+		r.logger.FromContext(ctx).Error("Error recording account erasure failure", zap.Error(err), zap.String("userID", userID.String()))
+		return fmt.Errorf("FailErasure: update error: %w", err)
+	}
+	return nil
+}
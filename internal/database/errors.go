@@ -0,0 +1,25 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// pqUniqueViolation is the SQLSTATE Postgres reports for a unique constraint violation.
+const pqUniqueViolation = "23505"
+
+// Unique constraints on users, named by Postgres' <table>_<column>_key default.
+const (
+	constraintUsersEmail    = "users_email_key"
+	constraintUsersUsername = "users_username_key"
+)
+
+// uniqueViolation reports which unique constraint err violated, if any.
+func uniqueViolation(err error) (constraint string, ok bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+		return pqErr.Constraint, true
+	}
+	return "", false
+}
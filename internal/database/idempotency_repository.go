@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/pkg/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// idempotencyColumns are the columns of models.IdempotencyRecord.
+const idempotencyColumns = `user_id, idempotency_key, request_fingerprint, locked_at, completed_at, status_code, content_type, response_body, expires_at`
+
+// reserveAttempts bounds Reserve's retries when a conflicting key disappears before it can be read.
+const reserveAttempts = 3
+
+// idempotencyRepositoryImpl implements the models.IdempotencyRepository interface.
+type idempotencyRepositoryImpl struct {
+	db     *Cluster
+	logger *logger.Logger
+}
+
+// NewIdempotencyRepository creates a new instance that implements models.IdempotencyRepository.
+func NewIdempotencyRepository(db *Cluster, appLogger *logger.Logger) models.IdempotencyRepository {
+	return &idempotencyRepositoryImpl{
+		db:     db,
+		logger: appLogger,
+	}
+}
+
+// Reserve inserts an in-progress row for the key. The primary key makes this
+// atomic, so of two concurrent requests with one key only one gets to run.
+func (r *idempotencyRepositoryImpl) Reserve(ctx context.Context, userID uuid.UUID, key, fingerprint string, ttl, lockTimeout time.Duration) (*models.IdempotencyRecord, bool, error) {
+	for attempt := 0; attempt < reserveAttempts; attempt++ {
+		// Free the key if it expired or its request was abandoned (e.g. the instance died mid-request)
+		_, err := r.db.writer(ctx).ExecContext(ctx,
+			`DELETE FROM idempotency_keys
+			  WHERE user_id = $1 AND idempotency_key = $2
+				AND (expires_at <= NOW() OR (completed_at IS NULL AND locked_at + $3::double precision * INTERVAL '1 second' <= NOW()))`,
+			userID, key, lockTimeout.Seconds())
+		if err != nil {
+			r.logger.FromContext(ctx).Error("Error freeing stale idempotency key", zap.Error(err), zap.String("userID", userID.String()))
+			return nil, false, fmt.Errorf("Reserve: delete error: %w", err)
+		}
+
+		var record models.IdempotencyRecord
+		err = r.db.writer(ctx).GetContext(ctx, &record,
+			`INSERT INTO idempotency_keys (user_id, idempotency_key, request_fingerprint, expires_at)
+			 VALUES ($1, $2, $3, NOW() + $4::double precision * INTERVAL '1 second')
+			 ON CONFLICT (user_id, idempotency_key) DO NOTHING
+			 RETURNING `+idempotencyColumns,
+			userID, key, fingerprint, ttl.Seconds())
+		if err == nil {
+			return &record, true, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) { // No row means the key is taken
+			r.logger.FromContext(ctx).Error("Error reserving idempotency key", zap.Error(err), zap.String("userID", userID.String()))
+			return nil, false, fmt.Errorf("Reserve: insert error: %w", err)
+		}
+
+		err = r.db.writer(ctx).GetContext(ctx, &record,
+			`SELECT `+idempotencyColumns+` FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2`,
+			userID, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // Released between the insert and the select; try again
+		}
+		if err != nil {
+			r.logger.FromContext(ctx).Error("Error fetching idempotency key", zap.Error(err), zap.String("userID", userID.String()))
+			return nil, false, fmt.Errorf("Reserve: query error: %w", err)
+		}
+		return &record, false, nil
+	}
+	return nil, false, fmt.Errorf("Reserve: key %q kept changing, gave up after %d attempts", key, reserveAttempts)
+}
+
+// Complete records the response for a reserved key. The key is matched on
+// locked_at too, so a request whose reservation was taken over after
+// lockTimeout does not overwrite the reservation of the request that took it.
+func (r *idempotencyRepositoryImpl) Complete(ctx context.Context, userID uuid.UUID, key string, lockedAt time.Time, statusCode int, contentType string, body []byte) error {
+	res, err := r.db.writer(ctx).ExecContext(ctx,
+		`UPDATE idempotency_keys SET completed_at = NOW(), status_code = $4, content_type = $5, response_body = $6
+		  WHERE user_id = $1 AND idempotency_key = $2 AND locked_at = $3 AND completed_at IS NULL`,
+		userID, key, lockedAt, statusCode, contentType, body)
+	if err != nil {
+		r.logger.FromContext(ctx).Error("Error storing idempotent response", zap.Error(err), zap.String("userID", userID.String()))
+		return fmt.Errorf("Complete: update error: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("Complete: key %q is no longer reserved by this request: %w", key, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// Release deletes a reserved key, unless another request has reserved it since.
+func (r *idempotencyRepositoryImpl) Release(ctx context.Context, userID uuid.UUID, key string, lockedAt time.Time) error {
+	_, err := r.db.writer(ctx).ExecContext(ctx,
+		`DELETE FROM idempotency_keys
+		  WHERE user_id = $1 AND idempotency_key = $2 AND locked_at = $3 AND completed_at IS NULL`,
+		userID, key, lockedAt)
+	if err != nil {
+		r.logger.FromContext(ctx).Error("Error releasing idempotency key", zap.Error(err), zap.String("userID", userID.String()))
+		return fmt.Errorf("Release: delete error: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes every expired key.
+func (r *idempotencyRepositoryImpl) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := r.db.writer(ctx).ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		r.logger.FromContext(ctx).Error("Error deleting expired idempotency keys", zap.Error(err))
+		return 0, fmt.Errorf("DeleteExpired: delete error: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/auth_service/internal/metrics"
+	"example.com/auth_service/internal/tracing"
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Query targets used as the metrics label.
+const (
+	targetPrimary = "primary"
+	targetReplica = "replica"
+	targetTx      = "tx"
+)
+
+// instrumentedExecutor traces every query sent through next and records its latency.
+type instrumentedExecutor struct {
+	next   dbtx
+	target string
+}
+
+// start opens a client span for one query.
+func (e instrumentedExecutor) start(ctx context.Context, kind, query string) (context.Context, trace.Span, time.Time) {
+	ctx, span := tracing.Tracer().Start(ctx, "db."+kind,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", query),
+			attribute.String("db.target", e.target),
+		))
+	return ctx, span, time.Now()
+}
+
+// finish ends the span and records the query latency. sql.ErrNoRows is a
+// result, not a failure.
+func (e instrumentedExecutor) finish(span trace.Span, kind string, start time.Time, err error) {
+	metrics.ObserveDBQuery(e.target, kind, time.Since(start))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+func (e instrumentedExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span, start := e.start(ctx, "exec", query)
+	res, err := e.next.ExecContext(ctx, query, args...)
+	e.finish(span, "exec", start, err)
+	return res, err
+}
+
+func (e instrumentedExecutor) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, span, start := e.start(ctx, "get", query)
+	err := e.next.GetContext(ctx, dest, query, args...)
+	e.finish(span, "get", start, err)
+	return err
+}
+
+func (e instrumentedExecutor) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, span, start := e.start(ctx, "select", query)
+	err := e.next.SelectContext(ctx, dest, query, args...)
+	e.finish(span, "select", start, err)
+	return err
+}
+
+// QueryRowxContext measures the round trip; errors surface later, from Scan.
+func (e instrumentedExecutor) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	ctx, span, start := e.start(ctx, "query", query)
+	row := e.next.QueryRowxContext(ctx, query, args...)
+	e.finish(span, "query", start, row.Err())
+	return row
+}
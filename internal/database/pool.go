@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"example.com/auth_service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// PoolStats is a snapshot of the connection pool, as reported by database/sql.
+type PoolStats struct {
+	MaxOpen           int           `json:"max_open"`
+	Open              int           `json:"open"`
+	InUse             int           `json:"in_use"`
+	Idle              int           `json:"idle"`
+	WaitCount         int64         `json:"wait_count"`    // Total waits for a free connection
+	WaitDuration      time.Duration `json:"wait_duration"` // Total time spent waiting
+	MaxIdleClosed     int64         `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64         `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64         `json:"max_lifetime_closed"`
+}
+
+// Stats returns the current pool statistics for db.
+func Stats(db *sqlx.DB) PoolStats {
+	s := db.Stats()
+	return PoolStats{
+		MaxOpen:           s.MaxOpenConnections,
+		Open:              s.OpenConnections,
+		InUse:             s.InUse,
+		Idle:              s.Idle,
+		WaitCount:         s.WaitCount,
+		WaitDuration:      s.WaitDuration,
+		MaxIdleClosed:     s.MaxIdleClosed,
+		MaxIdleTimeClosed: s.MaxIdleTimeClosed,
+		MaxLifetimeClosed: s.MaxLifetimeClosed,
+	}
+}
+
+// MonitorPool logs pool statistics every interval until ctx is cancelled.
+// Requests that had to wait for a connection since the last tick are logged
+// as a warning, since they mean the pool is too small for the load.
+func MonitorPool(ctx context.Context, db *sqlx.DB, interval time.Duration, appLogger *logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := Stats(db)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cur := Stats(db)
+			if waits := cur.WaitCount - last.WaitCount; waits > 0 {
+				appLogger.Warn("Database pool saturated",
+					zap.Int("open", cur.Open),
+					zap.Int("in_use", cur.InUse),
+					zap.Int64("new_waits", waits),
+					zap.Duration("new_wait_duration", cur.WaitDuration-last.WaitDuration))
+			} else {
+				appLogger.Debug("Database pool stats",
+					zap.Int("open", cur.Open),
+					zap.Int("in_use", cur.InUse),
+					zap.Int("idle", cur.Idle),
+					zap.Int64("wait_count", cur.WaitCount),
+					zap.Duration("wait_duration", cur.WaitDuration))
+			}
+			last = cur
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"example.com/auth_service/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// txKey is the context key under which WithinTx stores the active transaction.
+type txKey struct{}
+
+// dbtx is the subset of *sqlx.DB and *sqlx.Tx used by the repositories.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+	QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row
+}
+
+// TxManager runs functions inside a database transaction shared by all repositories.
+type TxManager struct {
+	db *Cluster
+}
+
+var _ models.TxManager = (*TxManager)(nil)
+
+// NewTxManager creates a new TxManager.
+func NewTxManager(db *Cluster) *TxManager {
+	return &TxManager{db: db}
+}
+
+// WithinTx runs fn in a transaction on the primary, committing if it returns nil and rolling
+// back otherwise. Repository calls made with the ctx passed to fn use that
+// transaction. If ctx already carries one, fn simply joins it.
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withinTx(ctx, m.db.Primary, fn)
+}
+
+func withinTx(ctx context.Context, db *sqlx.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after Commit
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
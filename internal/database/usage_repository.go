@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/pkg/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// usageRepositoryImpl implements the models.UsageRepository interface.
+type usageRepositoryImpl struct {
+	db     *Cluster
+	logger *logger.Logger
+}
+
+// NewUsageRepository creates a new instance that implements models.UsageRepository.
+func NewUsageRepository(db *Cluster, appLogger *logger.Logger) models.UsageRepository {
+	return &usageRepositoryImpl{
+		db:     db,
+		logger: appLogger,
+	}
+}
+
+// GetUsage retrieves a user's usage counters.
+func (r *usageRepositoryImpl) GetUsage(ctx context.Context, userID uuid.UUID) (*models.Usage, error) {
+	var usage models.Usage
+	query := `SELECT user_id, bytes_stored, file_count, period_start, uploads_in_period, updated_at
+			  FROM user_usage WHERE user_id = $1`
+	err := r.db.reader(ctx).GetContext(ctx, &usage, query, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		now := time.Now()
+		return &models.Usage{UserID: userID, PeriodStart: now, UpdatedAt: now}, nil
+	}
+	if err != nil {
+		r.logger.FromContext(ctx).Error("Error fetching usage from DB", zap.Error(err), zap.String("userID", userID.String()))
+		return nil, fmt.Errorf("GetUsage: query error: %w", err)
+	}
+	return &usage, nil
+}
+
+// ReserveUpload counts an upload against the user's quota in a single
+// conditional UPDATE, so concurrent uploads cannot overshoot a limit.
+func (r *usageRepositoryImpl) ReserveUpload(ctx context.Context, userID uuid.UUID, sizeBytes int64, limits models.QuotaLimits, period time.Duration) (*models.Usage, error) {
+	if _, err := r.db.writer(ctx).ExecContext(ctx, `INSERT INTO user_usage (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`, userID); err != nil {
+		r.logger.FromContext(ctx).Error("Error initialising usage row", zap.Error(err), zap.String("userID", userID.String()))
+		return nil, fmt.Errorf("ReserveUpload: failed to initialise usage: %w", err)
+	}
+
+	// $5 is the period in seconds; a row whose period has elapsed starts a new one.
+	query := `UPDATE user_usage SET
+				bytes_stored = bytes_stored + $2,
+				file_count = file_count + 1,
+				period_start = CASE WHEN period_start + $5::double precision * INTERVAL '1 second' <= NOW() THEN NOW() ELSE period_start END,
+				uploads_in_period = CASE WHEN period_start + $5::double precision * INTERVAL '1 second' <= NOW() THEN 1 ELSE uploads_in_period + 1 END,
+				updated_at = NOW()
+			  WHERE user_id = $1
+				AND ($3::bigint = 0 OR bytes_stored + $2 <= $3)
+				AND ($4::integer = 0 OR file_count + 1 <= $4)
+				AND ($6::integer = 0 OR period_start + $5::double precision * INTERVAL '1 second' <= NOW() OR uploads_in_period + 1 <= $6)
+			  RETURNING user_id, bytes_stored, file_count, period_start, uploads_in_period, updated_at`
+
+	var usage models.Usage
+	err := r.db.writer(ctx).GetContext(ctx, &usage, query,
+		userID, sizeBytes, limits.MaxBytes, limits.MaxFiles, period.Seconds(), limits.MaxUploadsPerPeriod)
+	if err == nil {
+		return &usage, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		r.logger.FromContext(ctx).Error("Error reserving upload quota", zap.Error(err), zap.String("userID", userID.String()))
+		return nil, fmt.Errorf("ReserveUpload: update error: %w", err)
+	}
+
+	// Nothing was updated, so some limit would be exceeded. Work out which one.
+	current, err := r.GetUsage(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ReserveUpload: %w", err)
+	}
+	periodEnd := current.PeriodStart.Add(period)
+	switch {
+	case limits.MaxBytes > 0 && current.BytesStored+sizeBytes > limits.MaxBytes:
+		return nil, &models.QuotaExceededError{Kind: models.QuotaBytes, Limit: limits.MaxBytes}
+	case limits.MaxFiles > 0 && current.FileCount+1 > limits.MaxFiles:
+		return nil, &models.QuotaExceededError{Kind: models.QuotaFiles, Limit: int64(limits.MaxFiles)}
+	default:
+		return nil, &models.QuotaExceededError{
+			Kind:       models.QuotaUploads,
+			Limit:      int64(limits.MaxUploadsPerPeriod),
+			RetryAfter: time.Until(periodEnd),
+		}
+	}
+}
+
+// CancelUpload reverts a reservation made by ReserveUpload.
+func (r *usageRepositoryImpl) CancelUpload(ctx context.Context, userID uuid.UUID, sizeBytes int64) error {
+	query := `UPDATE user_usage SET
+				bytes_stored = GREATEST(bytes_stored - $2, 0),
+				file_count = GREATEST(file_count - 1, 0),
+				uploads_in_period = GREATEST(uploads_in_period - 1, 0),
+				updated_at = NOW()
+			  WHERE user_id = $1`
+	if _, err := r.db.writer(ctx).ExecContext(ctx, query, userID, sizeBytes); err != nil {
+		r.logger.FromContext(ctx).Error("Error cancelling upload quota reservation", zap.Error(err), zap.String("userID", userID.String()))
+		return fmt.Errorf("CancelUpload: update error: %w", err)
+	}
+	return nil
+}
+
+// RemoveStored subtracts a deleted file from the user's stored totals.
+// The per-period upload count is left alone: the upload still happened.
+func (r *usageRepositoryImpl) RemoveStored(ctx context.Context, userID uuid.UUID, sizeBytes int64) error {
+	query := `UPDATE user_usage SET
+				bytes_stored = GREATEST(bytes_stored - $2, 0),
+				file_count = GREATEST(file_count - 1, 0),
+				updated_at = NOW()
+			  WHERE user_id = $1`
+	if _, err := r.db.writer(ctx).ExecContext(ctx, query, userID, sizeBytes); err != nil {
+		r.logger.FromContext(ctx).Error("Error removing stored file from usage", zap.Error(err), zap.String("userID", userID.String()))
+		return fmt.Errorf("RemoveStored: update error: %w", err)
+	}
+	return nil
+}
+
+// ResetPeriod restarts the user's upload period. A user without a usage row
+// has nothing to reset and gets zeroed counters, as from GetUsage.
+func (r *usageRepositoryImpl) ResetPeriod(ctx context.Context, userID uuid.UUID) (*models.Usage, error) {
+	var usage models.Usage
+	query := `UPDATE user_usage SET period_start = NOW(), uploads_in_period = 0, updated_at = NOW()
+			  WHERE user_id = $1
+			  RETURNING user_id, bytes_stored, file_count, period_start, uploads_in_period, updated_at`
+	err := r.db.writer(ctx).GetContext(ctx, &usage, query, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		now := time.Now()
+		return &models.Usage{UserID: userID, PeriodStart: now, UpdatedAt: now}, nil
+	}
+	if err != nil {
+		r.logger.FromContext(ctx).Error("Error resetting upload period", zap.Error(err), zap.String("userID", userID.String()))
+		return nil, fmt.Errorf("ResetPeriod: update error: %w", err)
+	}
+	r.logger.FromContext(ctx).Info("Upload period reset", zap.String("userID", userID.String()))
+	return &usage, nil
+}
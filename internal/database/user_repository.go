@@ -1,24 +1,31 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"example.com/auth_service/internal/models"
 	"example.com/auth_service/pkg/logger"
-	"github.com/jmoiron/sqlx"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+// userColumns are the users columns scanned into models.User.
+const userColumns = `id, username, email, password_hash, role, created_at, updated_at, pending_email, sessions_valid_after, deleted_at,
+	disabled_at, disabled_reason, retention_days`
+
 // userRepositoryImpl implements the models.UserRepository interface.
 type userRepositoryImpl struct {
-	db     *sqlx.DB
+	db     *Cluster
 	logger *logger.Logger // Use our logger type
 }
 
 // NewUserRepository creates a new instance that implements models.UserRepository.
-func NewUserRepository(db *sqlx.DB, appLogger *logger.Logger) models.UserRepository { // Accept logger
+func NewUserRepository(db *Cluster, appLogger *logger.Logger) models.UserRepository { // Accept logger
 	return &userRepositoryImpl{
 		db:     db,
 		logger: appLogger, // Assign logger
@@ -26,50 +33,263 @@ func NewUserRepository(db *sqlx.DB, appLogger *logger.Logger) models.UserReposit
 }
 
 // CreateUser inserts a new user into the database.
-func (r *userRepositoryImpl) CreateUser(user *models.User) error {
-	query := `INSERT INTO users (id, username, email, password_hash, created_at, updated_at)
-			  VALUES ($1, $2, $3, $4, $5, $6)`
-	_, err := r.db.Exec(query, user.ID, user.Username, user.Email, user.Password, user.CreatedAt, user.UpdatedAt)
+// Returns models.ErrEmailTaken or models.ErrUsernameTaken on a unique violation.
+func (r *userRepositoryImpl) CreateUser(ctx context.Context, user *models.User) error {
+	query := `INSERT INTO users (id, username, email, password_hash, role, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.db.writer(ctx).ExecContext(ctx, query, user.ID, user.Username, user.Email, user.Password, user.Role, user.CreatedAt, user.UpdatedAt)
 	if err != nil {
-		r.logger.Error("Error creating user in DB", zap.Error(err), zap.String("email", user.Email)) // Use logger
+		if constraint, ok := uniqueViolation(err); ok {
+			switch constraint {
+			case constraintUsersEmail:
+				return models.ErrEmailTaken
+			case constraintUsersUsername:
+				return models.ErrUsernameTaken
+			}
+		}
+		r.logger.FromContext(ctx).Error("Error creating user in DB", zap.Error(err), zap.String("email", user.Email)) // Use logger
 		return fmt.Errorf("CreateUser: failed to insert user: %w", err)
 	}
-	r.logger.Info("User successfully created in DB", zap.String("userID", user.ID)) // Use logger
+	r.logger.FromContext(ctx).Info("User successfully created in DB", zap.String("userID", user.ID)) // Use logger
 	return nil
 }
 
 // GetUserByEmail retrieves a user from the database by their email.
 // Returns sql.ErrNoRows if no user is found.
-func (r *userRepositoryImpl) GetUserByEmail(email string) (*models.User, error) {
+func (r *userRepositoryImpl) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	query := `SELECT id, username, email, password_hash, created_at, updated_at FROM users WHERE email = $1`
-	err := r.db.Get(&user, query, email)
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+	err := r.db.reader(ctx).GetContext(ctx, &user, query, email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			r.logger.Debug("User not found by email", zap.String("email", email)) // Use logger
-			return nil, err                                                       // Return sql.ErrNoRows directly for service layer to check
+			r.logger.FromContext(ctx).Debug("User not found by email", zap.String("email", email)) // Use logger
+			return nil, err                                                                        // Return sql.ErrNoRows directly for service layer to check
 		}
-		r.logger.Error("Error fetching user by email from DB", zap.Error(err), zap.String("email", email)) // Use logger
+		r.logger.FromContext(ctx).Error("Error fetching user by email from DB", zap.Error(err), zap.String("email", email)) // Use logger
 		return nil, fmt.Errorf("GetUserByEmail: query error: %w", err)
 	}
-	r.logger.Debug("User found by email", zap.String("email", email), zap.String("userID", user.ID)) // Use logger
+	r.logger.FromContext(ctx).Debug("User found by email", zap.String("email", email), zap.String("userID", user.ID)) // Use logger
 	return &user, nil
 }
 
 // GetUserByID retrieves a user from the database by their ID.
 // Returns sql.ErrNoRows if no user is found.
-func (r *userRepositoryImpl) GetUserByID(id string) (*models.User, error) {
+func (r *userRepositoryImpl) GetUserByID(ctx context.Context, id string) (*models.User, error) {
 	var user models.User
-	query := `SELECT id, username, email, password_hash, created_at, updated_at FROM users WHERE id = $1`
-	err := r.db.Get(&user, query, id)
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	err := r.db.reader(ctx).GetContext(ctx, &user, query, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			r.logger.Debug("User not found by ID", zap.String("userID", id)) // Use logger
-			return nil, err                                                  // Return sql.ErrNoRows directly
+			r.logger.FromContext(ctx).Debug("User not found by ID", zap.String("userID", id)) // Use logger
+			return nil, err                                                                   // Return sql.ErrNoRows directly
 		}
-		r.logger.Error("Error fetching user by ID from DB", zap.Error(err), zap.String("userID", id)) // Use logger
+		r.logger.FromContext(ctx).Error("Error fetching user by ID from DB", zap.Error(err), zap.String("userID", id)) // Use logger
 		return nil, fmt.Errorf("GetUserByID: query error: %w", err)
 	}
-	r.logger.Debug("User found by ID", zap.String("userID", id)) // Use logger
+	r.logger.FromContext(ctx).Debug("User found by ID", zap.String("userID", id)) // Use logger
+	return &user, nil
+}
+
+// UpdateUsername changes a user's username. updated_at is set by the users trigger.
+// Returns models.ErrUsernameTaken on a unique violation and sql.ErrNoRows if the user does not exist.
+func (r *userRepositoryImpl) UpdateUsername(ctx context.Context, id, username string) (*models.User, error) {
+	var user models.User
+	query := `UPDATE users SET username = $2 WHERE id = $1 RETURNING ` + userColumns
+	err := r.db.writer(ctx).GetContext(ctx, &user, query, id, username)
+	if err != nil {
+		if constraint, ok := uniqueViolation(err); ok && constraint == constraintUsersUsername {
+			return nil, models.ErrUsernameTaken
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		r.logger.FromContext(ctx).Error("Error updating username in DB", zap.Error(err), zap.String("userID", id))
+		return nil, fmt.Errorf("UpdateUsername: update error: %w", err)
+	}
+	r.logger.FromContext(ctx).Info("Username updated in DB", zap.String("userID", id))
+	return &user, nil
+}
+
+// RequestEmailChange records a pending email and its verification token in one
+// statement. Earlier tokens for the user are deleted, so only the latest request can be confirmed.
+func (r *userRepositoryImpl) RequestEmailChange(ctx context.Context, id, email, tokenHash string, expiresAt time.Time) (*models.User, error) {
+	var user models.User
+	query := `WITH cleared AS (
+				DELETE FROM email_verifications WHERE user_id = $1
+			  ), token AS (
+				INSERT INTO email_verifications (token_hash, user_id, email, expires_at) VALUES ($3, $1, $2, $4)
+			  )
+			  UPDATE users SET pending_email = $2 WHERE id = $1 RETURNING ` + userColumns
+	err := r.db.writer(ctx).GetContext(ctx, &user, query, id, email, tokenHash, expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		r.logger.FromContext(ctx).Error("Error recording email change in DB", zap.Error(err), zap.String("userID", id))
+		return nil, fmt.Errorf("RequestEmailChange: query error: %w", err)
+	}
+	r.logger.FromContext(ctx).Info("Email change requested", zap.String("userID", id))
 	return &user, nil
 }
+
+// ConfirmEmailChange consumes a verification token and moves the pending email
+// into users.email. An expired token is consumed without changing the email.
+func (r *userRepositoryImpl) ConfirmEmailChange(ctx context.Context, tokenHash string) (*models.User, error) {
+	var user models.User
+	query := `WITH v AS (
+				DELETE FROM email_verifications WHERE token_hash = $1 RETURNING user_id, email, expires_at
+			  )
+			  UPDATE users u SET email = v.email, pending_email = NULL
+			  FROM v
+			  WHERE u.id = v.user_id AND u.pending_email = v.email AND v.expires_at > NOW()
+			  RETURNING ` + qualify("u", userColumns)
+	err := r.db.writer(ctx).GetContext(ctx, &user, query, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrVerificationInvalid
+		}
+		if constraint, ok := uniqueViolation(err); ok && constraint == constraintUsersEmail {
+			return nil, models.ErrEmailTaken
+		}
+		r.logger.FromContext(ctx).Error("Error confirming email change in DB", zap.Error(err))
+		return nil, fmt.Errorf("ConfirmEmailChange: query error: %w", err)
+	}
+	r.logger.FromContext(ctx).Info("Email change confirmed", zap.String("userID", user.ID))
+	return &user, nil
+}
+
+// UpdatePassword stores a new password hash and moves sessions_valid_after forward.
+// Returns sql.ErrNoRows if the user does not exist.
+func (r *userRepositoryImpl) UpdatePassword(ctx context.Context, id, passwordHash string, sessionsValidAfter time.Time) error {
+	res, err := r.db.writer(ctx).ExecContext(ctx,
+		`UPDATE users SET password_hash = $2, sessions_valid_after = $3 WHERE id = $1`, id, passwordHash, sessionsValidAfter)
+	if err != nil {
+		r.logger.FromContext(ctx).Error("Error updating password in DB", zap.Error(err), zap.String("userID", id))
+		return fmt.Errorf("UpdatePassword: update error: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	r.logger.FromContext(ctx).Info("Password updated in DB", zap.String("userID", id))
+	return nil
+}
+
+// SearchUsers returns a page of users matching filter, newest first, and the
+// total number of matches.
+func (r *userRepositoryImpl) SearchUsers(ctx context.Context, filter models.UserFilter, limit, offset int) ([]models.User, int, error) {
+	where, args := userFilterClause(filter)
+	var total int
+	if err := r.db.reader(ctx).GetContext(ctx, &total, `SELECT COUNT(*) FROM users`+where, args...); err != nil {
+		r.logger.FromContext(ctx).Error("Error counting users in DB", zap.Error(err))
+		return nil, 0, fmt.Errorf("SearchUsers: count error: %w", err)
+	}
+
+	users := []models.User{}
+	query := `SELECT ` + userColumns + ` FROM users` + where +
+		fmt.Sprintf(` ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+	if err := r.db.reader(ctx).SelectContext(ctx, &users, query, append(args, limit, offset)...); err != nil {
+		r.logger.FromContext(ctx).Error("Error searching users in DB", zap.Error(err))
+		return nil, 0, fmt.Errorf("SearchUsers: query error: %w", err)
+	}
+	return users, total, nil
+}
+
+// userFilterClause builds the WHERE clause and arguments for filter.
+func userFilterClause(filter models.UserFilter) (string, []any) {
+	var conds []string
+	var args []any
+	// add appends cond with each ? bound to arg.
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, strings.ReplaceAll(cond, "?", fmt.Sprintf("$%d", len(args))))
+	}
+	if filter.Query != "" {
+		if id, err := uuid.Parse(filter.Query); err == nil {
+			add(`id = ?`, id)
+		} else {
+			add(`(username ILIKE ? ESCAPE '\' OR email ILIKE ? ESCAPE '\')`, "%"+likeEscaper.Replace(filter.Query)+"%")
+		}
+	}
+	if filter.Role != "" {
+		add(`role = ?`, filter.Role)
+	}
+	switch filter.Status {
+	case models.UserStatusActive:
+		conds = append(conds, `disabled_at IS NULL AND deleted_at IS NULL`)
+	case models.UserStatusDisabled:
+		conds = append(conds, `disabled_at IS NOT NULL`)
+	case models.UserStatusDeleted:
+		conds = append(conds, `deleted_at IS NOT NULL`)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return ` WHERE ` + strings.Join(conds, ` AND `), args
+}
+
+// likeEscaper escapes the LIKE wildcards in a literal search string.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// DisableUser sets disabled_at, keeping the original time if the user is
+// already disabled, and moves sessions_valid_after forward so that enabling
+// the user again does not bring back tokens issued before.
+// Returns sql.ErrNoRows if the user does not exist.
+func (r *userRepositoryImpl) DisableUser(ctx context.Context, id, reason string, sessionsValidAfter time.Time) (*models.User, error) {
+	var user models.User
+	query := `UPDATE users SET disabled_at = COALESCE(disabled_at, NOW()), disabled_reason = $2,
+				sessions_valid_after = $3
+			  WHERE id = $1 RETURNING ` + userColumns
+	err := r.db.writer(ctx).GetContext(ctx, &user, query, id, reason, sessionsValidAfter)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		r.logger.FromContext(ctx).Error("Error disabling user in DB", zap.Error(err), zap.String("userID", id))
+		return nil, fmt.Errorf("DisableUser: update error: %w", err)
+	}
+	r.logger.FromContext(ctx).Info("User disabled in DB", zap.String("userID", id))
+	return &user, nil
+}
+
+// EnableUser clears disabled_at and disabled_reason.
+// Returns sql.ErrNoRows if the user does not exist.
+func (r *userRepositoryImpl) EnableUser(ctx context.Context, id string) (*models.User, error) {
+	var user models.User
+	query := `UPDATE users SET disabled_at = NULL, disabled_reason = NULL WHERE id = $1 RETURNING ` + userColumns
+	err := r.db.writer(ctx).GetContext(ctx, &user, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		r.logger.FromContext(ctx).Error("Error enabling user in DB", zap.Error(err), zap.String("userID", id))
+		return nil, fmt.Errorf("EnableUser: update error: %w", err)
+	}
+	r.logger.FromContext(ctx).Info("User enabled in DB", zap.String("userID", id))
+	return &user, nil
+}
+
+// SetRetentionDays sets users.retention_days, or clears it when days is nil.
+// Returns sql.ErrNoRows if the user does not exist.
+func (r *userRepositoryImpl) SetRetentionDays(ctx context.Context, id string, days *int) (*models.User, error) {
+	var user models.User
+	query := `UPDATE users SET retention_days = $2 WHERE id = $1 RETURNING ` + userColumns
+	err := r.db.writer(ctx).GetContext(ctx, &user, query, id, days)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		r.logger.FromContext(ctx).Error("Error setting retention in DB", zap.Error(err), zap.String("userID", id))
+		return nil, fmt.Errorf("SetRetentionDays: update error: %w", err)
+	}
+	r.logger.FromContext(ctx).Info("User retention updated in DB", zap.String("userID", id))
+	return &user, nil
+}
+
+// qualify prefixes each of a comma-separated list of columns with table.
+func qualify(table, columns string) string {
+	parts := strings.Split(columns, ", ")
+	for i, c := range parts {
+		parts[i] = table + "." + c
+	}
+	return strings.Join(parts, ", ")
+}
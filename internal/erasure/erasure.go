@@ -0,0 +1,148 @@
+// Package erasure deletes the accounts of users who asked for it.
+package erasure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/auth_service/internal/database"
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/internal/storage"
+	"example.com/auth_service/pkg/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// jobLease is how long a claimed job is held before another run may retry it.
+const jobLease = 15 * time.Minute
+
+// Eraser runs account erasure jobs. For each job it deletes the user's audio
+// files, releasing their content-addressed blobs (the object itself is deleted
+// only when no other file shares it), then every object under the "<user_id>/"
+// prefix used before deduplication, and finally the users row, which cascades
+// to the user's remaining rows.
+type Eraser struct {
+	store       storage.BlobStore
+	audioRepo   models.AudioRepository
+	erasureRepo models.ErasureRepository
+	logger      *logger.Logger
+}
+
+// NewEraser creates a new Eraser.
+func NewEraser(store storage.BlobStore, audioRepo models.AudioRepository, erasureRepo models.ErasureRepository, appLogger *logger.Logger) *Eraser {
+	return &Eraser{
+		store:       store,
+		audioRepo:   audioRepo,
+		erasureRepo: erasureRepo,
+		logger:      appLogger,
+	}
+}
+
+// Run processes pending jobs until none is left and returns how many completed.
+// A job that fails is logged, kept, and retried after its lease expires.
+func (e *Eraser) Run(ctx context.Context) (int, error) {
+	completed := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return completed, err
+		}
+		job, err := e.erasureRepo.ClaimErasure(ctx, jobLease)
+		if err != nil {
+			return completed, fmt.Errorf("account erasure: %w", err)
+		}
+		if job == nil {
+			return completed, nil
+		}
+
+		if err := e.erase(ctx, job.UserID); err != nil {
+			e.logger.Error("Account erasure failed, will retry", zap.String("userID", job.UserID.String()), zap.Int("attempt", job.Attempts), zap.Error(err))
+			if ferr := e.erasureRepo.FailErasure(context.WithoutCancel(ctx), job.UserID, err.Error()); ferr != nil {
+				e.logger.Error("Failed to record account erasure failure", zap.String("userID", job.UserID.String()), zap.Error(ferr))
+			}
+			continue
+		}
+		completed++
+	}
+}
+
+// RunPeriodically runs pending jobs every interval until ctx is cancelled.
+func (e *Eraser) RunPeriodically(ctx context.Context, interval time.Duration) {
+	e.logger.Info("Account eraser started", zap.Duration("interval", interval))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e.logger.Info("Account eraser stopped")
+			return
+		case <-ticker.C:
+			if _, err := e.Run(ctx); err != nil && ctx.Err() == nil {
+				e.logger.Error("Account erasure run failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// erase deletes one account. Each step is idempotent, so a retry after a
+// partial failure picks up where the previous attempt stopped. Individual
+// deletions are not interrupted by cancellation; the job is checked between them.
+func (e *Eraser) erase(ctx context.Context, userID uuid.UUID) error {
+	stepCtx := context.WithoutCancel(ctx)
+	// The user can no longer upload, but read from the primary to see their latest files.
+	files, err := e.audioRepo.ListAudioFilesByUserID(database.WithPrimary(ctx), userID)
+	if err != nil {
+		return err
+	}
+
+	filesDeleted, objectsDeleted := 0, 0
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := e.audioRepo.DeleteAudioFile(stepCtx, file.ID, func(s3Key string) error {
+			if s3Key == "" {
+				return nil // Purged by retention; its object is already gone
+			}
+			if err := e.store.Delete(stepCtx, s3Key); err != nil {
+				return err
+			}
+			objectsDeleted++
+			return nil
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // Already deleted by an earlier attempt
+		}
+		if err != nil {
+			return fmt.Errorf("delete audio file %s: %w", file.ID, err)
+		}
+		filesDeleted++
+	}
+
+	// Objects uploaded before deduplication were keyed "<user_id>/<timestamp>/<name>".
+	var legacyKeys []string
+	err = e.store.List(ctx, userID.String()+"/", func(obj storage.ObjectInfo) error {
+		legacyKeys = append(legacyKeys, obj.Key)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("list objects under %s/: %w", userID, err)
+	}
+	for _, key := range legacyKeys {
+		if err := e.store.Delete(stepCtx, key); err != nil {
+			return err
+		}
+		objectsDeleted++
+	}
+
+	if err := e.erasureRepo.CompleteErasure(stepCtx, userID, filesDeleted, objectsDeleted); err != nil {
+		return err
+	}
+	e.logger.Info("Account erasure finished",
+		zap.String("userID", userID.String()),
+		zap.Int("files_deleted", filesDeleted),
+		zap.Int("objects_deleted", objectsDeleted))
+	return nil
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"example.com/auth_service/internal/apierror"
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/internal/storage"
+	"example.com/auth_service/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// AccountHandler handles data export and account deletion for the current user.
+type AccountHandler struct {
+	userRepo    models.UserRepository
+	usageRepo   models.UsageRepository
+	audioRepo   models.AudioRepository
+	erasureRepo models.ErasureRepository
+	blobStore   storage.BlobStore
+	logger      *logger.Logger
+}
+
+// NewAccountHandler creates a new AccountHandler.
+func NewAccountHandler(userRepo models.UserRepository, usageRepo models.UsageRepository, audioRepo models.AudioRepository, erasureRepo models.ErasureRepository, blobStore storage.BlobStore, appLogger *logger.Logger) *AccountHandler {
+	return &AccountHandler{
+		userRepo:    userRepo,
+		usageRepo:   usageRepo,
+		audioRepo:   audioRepo,
+		erasureRepo: erasureRepo,
+		blobStore:   blobStore,
+		logger:      appLogger,
+	}
+}
+
+// exportManifest describes the contents of a data export.
+type exportManifest struct {
+	UserID       uuid.UUID   `json:"user_id"`
+	GeneratedAt  time.Time   `json:"generated_at"`
+	IncludeAudio bool        `json:"include_audio"`
+	AudioFiles   int         `json:"audio_files"`
+	MissingAudio []uuid.UUID `json:"missing_audio,omitempty"` // Files whose stored object could not be found
+}
+
+// ExportData streams a ZIP of everything stored about the caller:
+// profile.json, usage.json, audio_files.json (all metadata, including files
+// purged by retention), manifest.json and, with ?audio=true, the original
+// audio under audio/.
+// GET /api/v1/users/me/export
+func (h *AccountHandler) ExportData(c *gin.Context) error {
+	ctx := c.Request.Context()
+	reqLogger := h.logger.FromContext(ctx)
+	_, userID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+	includeAudio, err := strconv.ParseBool(c.DefaultQuery("audio", "false"))
+	if err != nil {
+		return apierror.InvalidRequest("audio must be true or false.")
+	}
+
+	// Read all metadata before the first byte is written, while errors can still become problem responses.
+	user, err := h.userRepo.GetUserByID(ctx, userID.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return apierror.NotFound("User not found.")
+	}
+	if err != nil {
+		return apierror.Internal("Failed to export data.", err)
+	}
+	usage, err := h.usageRepo.GetUsage(ctx, userID)
+	if err != nil {
+		return apierror.Internal("Failed to export data.", err)
+	}
+	files, err := h.audioRepo.ListAudioFilesByUserID(ctx, userID)
+	if err != nil {
+		return apierror.Internal("Failed to export data.", err)
+	}
+	if files == nil {
+		files = []models.AudioFile{}
+	}
+
+	manifest := exportManifest{
+		UserID:       userID,
+		GeneratedAt:  time.Now().UTC(),
+		IncludeAudio: includeAudio,
+		AudioFiles:   len(files),
+	}
+	if includeAudio {
+		// Streaming every original can outlast HTTP_WRITE_TIMEOUT, so lift it for this
+		// response. The export rate limit keeps such responses rare.
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+			reqLogger.Debug("Could not lift write deadline for export", zap.Error(err))
+		}
+	}
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%s-%s.zip"`, userID, manifest.GeneratedAt.Format("20060102")))
+	c.Status(http.StatusOK)
+
+	zw := zip.NewWriter(c.Writer)
+	err = h.writeExport(c, zw, user, usage, files, &manifest)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		// The status is already sent; the client gets a truncated, unreadable archive.
+		reqLogger.Error("Data export failed mid-stream", zap.String("userID", userID.String()), zap.Error(err))
+		return nil
+	}
+	reqLogger.Info("Data exported",
+		zap.String("userID", userID.String()),
+		zap.Int("audio_files", len(files)),
+		zap.Bool("include_audio", includeAudio),
+		zap.Int("missing_audio", len(manifest.MissingAudio)))
+	return nil
+}
+
+// writeExport writes the archive entries. The manifest goes last, so it can list missing audio.
+func (h *AccountHandler) writeExport(c *gin.Context, zw *zip.Writer, user *models.User, usage *models.Usage, files []models.AudioFile, manifest *exportManifest) error {
+	if err := writeZipJSON(zw, "profile.json", user); err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "usage.json", usage); err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "audio_files.json", files); err != nil {
+		return err
+	}
+	if manifest.IncludeAudio {
+		for _, file := range files {
+			if file.S3Key == "" {
+				continue // Purged by retention
+			}
+			found, err := h.writeZipAudio(c, zw, &file)
+			if err != nil {
+				return fmt.Errorf("audio file %s: %w", file.ID, err)
+			}
+			if !found {
+				manifest.MissingAudio = append(manifest.MissingAudio, file.ID)
+			}
+		}
+	}
+	return writeZipJSON(zw, "manifest.json", manifest)
+}
+
+// writeZipAudio streams one stored object into the archive. Audio is already
+// compressed, so it is stored as is. It returns false if the object is missing.
+func (h *AccountHandler) writeZipAudio(c *gin.Context, zw *zip.Writer, file *models.AudioFile) (bool, error) {
+	body, _, err := h.blobStore.Get(c.Request.Context(), file.S3Key, nil)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer body.Close()
+
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     "audio/" + file.ID.String() + "_" + path.Base(file.OriginalFilename),
+		Method:   zip.Store,
+		Modified: file.UploadedAt,
+	})
+	if err != nil {
+		return false, err
+	}
+	_, err = io.Copy(w, body)
+	return true, err
+}
+
+// writeZipJSON adds v to the archive as indented JSON.
+func writeZipJSON(zw *zip.Writer, name string, v any) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// DeleteAccount schedules the caller's account for erasure. The account stops
+// working at once: its tokens are rejected and it can no longer log in. The
+// background eraser then deletes the audio, the stored objects and the rows.
+// DELETE /api/v1/users/me
+func (h *AccountHandler) DeleteAccount(c *gin.Context) error {
+	reqLogger := h.logger.FromContext(c.Request.Context())
+	_, userID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	job, err := h.erasureRepo.RequestErasure(c.Request.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apierror.NotFound("User not found.")
+	}
+	if err != nil {
+		return apierror.Internal("Failed to delete account.", err)
+	}
+
+	reqLogger.Info("Account deletion requested", zap.String("userID", userID.String()))
+	c.JSON(http.StatusAccepted, models.AccountDeletionResponse{
+		Status:      "pending",
+		RequestedAt: job.RequestedAt,
+	})
+	return nil
+}
@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"example.com/auth_service/internal/apierror"
+	"example.com/auth_service/internal/auth"
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/internal/middleware"
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/internal/storage"
+	"example.com/auth_service/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Page sizes for the admin list endpoints.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// AdminHandler serves the admin API: looking up and disabling users,
+// resetting quotas, and read access to any user's audio. Every route is
+// audited by middleware.Audit; handlers name the user they act on with
+// middleware.SetAuditTarget.
+type AdminHandler struct {
+	userRepo  models.UserRepository
+	usageRepo models.UsageRepository
+	audioRepo models.AudioRepository
+	auditRepo models.AuditRepository
+	blobStore storage.BlobStore
+	quota     config.QuotaConfig
+	logger    *logger.Logger
+}
+
+// NewAdminHandler creates a new AdminHandler.
+func NewAdminHandler(userRepo models.UserRepository, usageRepo models.UsageRepository, audioRepo models.AudioRepository, auditRepo models.AuditRepository, blobStore storage.BlobStore, quota config.QuotaConfig, appLogger *logger.Logger) *AdminHandler {
+	return &AdminHandler{
+		userRepo:  userRepo,
+		usageRepo: usageRepo,
+		audioRepo: audioRepo,
+		auditRepo: auditRepo,
+		blobStore: blobStore,
+		quota:     quota,
+		logger:    appLogger,
+	}
+}
+
+// ListUsers returns a page of users, newest first. q matches a substring of
+// the username or email, or an exact user ID; role and status narrow it down.
+// GET /api/v1/admin/users
+func (h *AdminHandler) ListUsers(c *gin.Context) error {
+	limit, offset, err := pagination(c)
+	if err != nil {
+		return err
+	}
+	filter := models.UserFilter{
+		Query:  strings.TrimSpace(c.Query("q")),
+		Role:   c.Query("role"),
+		Status: c.Query("status"),
+	}
+	switch filter.Status {
+	case "", models.UserStatusActive, models.UserStatusDisabled, models.UserStatusDeleted:
+	default:
+		return apierror.InvalidRequest("status must be active, disabled or deleted.")
+	}
+
+	users, total, err := h.userRepo.SearchUsers(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		return apierror.Internal("Failed to search users.", err)
+	}
+	list := models.AdminUserList{Users: make([]models.AdminUser, len(users)), Total: total, Limit: limit, Offset: offset}
+	for i := range users {
+		list.Users[i] = models.NewAdminUser(&users[i])
+	}
+	c.JSON(http.StatusOK, list)
+	return nil
+}
+
+// GetUser returns a user with their usage counters and quota limits.
+// The counters are as stored: an elapsed upload period is only reset by the next upload.
+// GET /api/v1/admin/users/:id
+func (h *AdminHandler) GetUser(c *gin.Context) error {
+	ctx := c.Request.Context()
+	userID, err := auditedUserID(c)
+	if err != nil {
+		return err
+	}
+	user, err := h.loadUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	usage, err := h.usageRepo.GetUsage(ctx, userID)
+	if err != nil {
+		return apierror.Internal("Failed to load usage.", err)
+	}
+	c.JSON(http.StatusOK, models.AdminUserDetail{
+		User:   models.NewAdminUser(user),
+		Usage:  *usage,
+		Limits: h.quota.LimitsFor(user.Role),
+	})
+	return nil
+}
+
+// DisableUser stops a user from logging in and ends their sessions at once.
+// Admins cannot disable themselves.
+// POST /api/v1/admin/users/:id/disable
+func (h *AdminHandler) DisableUser(c *gin.Context) error {
+	reqLogger := h.logger.FromContext(c.Request.Context())
+	_, actorID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+	userID, err := auditedUserID(c)
+	if err != nil {
+		return err
+	}
+	var req models.DisableUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return apierror.FromBinding(err)
+	}
+	middleware.SetAuditDetail(c, "reason", req.Reason)
+	if userID == actorID {
+		return apierror.Forbidden("You cannot disable your own account.")
+	}
+
+	user, err := h.userRepo.DisableUser(c.Request.Context(), userID.String(), req.Reason, auth.SessionCutoff())
+	if errors.Is(err, sql.ErrNoRows) {
+		return apierror.NotFound("User not found.")
+	}
+	if err != nil {
+		return apierror.Internal("Failed to disable user.", err)
+	}
+	reqLogger.Info("User disabled by admin", zap.String("userID", user.ID), zap.String("admin_id", actorID.String()))
+	c.JSON(http.StatusOK, models.NewAdminUser(user))
+	return nil
+}
+
+// EnableUser lets a disabled user log in again. Tokens issued before the
+// user was disabled stay revoked.
+// POST /api/v1/admin/users/:id/enable
+func (h *AdminHandler) EnableUser(c *gin.Context) error {
+	reqLogger := h.logger.FromContext(c.Request.Context())
+	userID, err := auditedUserID(c)
+	if err != nil {
+		return err
+	}
+	user, err := h.userRepo.EnableUser(c.Request.Context(), userID.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return apierror.NotFound("User not found.")
+	}
+	if err != nil {
+		return apierror.Internal("Failed to enable user.", err)
+	}
+	reqLogger.Info("User enabled by admin", zap.String("userID", user.ID))
+	c.JSON(http.StatusOK, models.NewAdminUser(user))
+	return nil
+}
+
+// ResetQuota starts a new upload period for the user, so they can upload
+// again before their period would have ended. Storage limits still apply.
+// POST /api/v1/admin/users/:id/quota/reset
+func (h *AdminHandler) ResetQuota(c *gin.Context) error {
+	ctx := c.Request.Context()
+	userID, err := auditedUserID(c)
+	if err != nil {
+		return err
+	}
+	if _, err := h.loadUser(ctx, userID); err != nil {
+		return err
+	}
+	usage, err := h.usageRepo.ResetPeriod(ctx, userID)
+	if err != nil {
+		return apierror.Internal("Failed to reset quota.", err)
+	}
+	h.logger.FromContext(ctx).Info("Upload quota reset by admin", zap.String("userID", userID.String()))
+	c.JSON(http.StatusOK, usage)
+	return nil
+}
+
+// SetRetention sets how long the user's audio is kept before the retention
+// sweeper purges it. A null retention_days restores the global default.
+// PUT /api/v1/admin/users/:id/retention
+func (h *AdminHandler) SetRetention(c *gin.Context) error {
+	userID, err := auditedUserID(c)
+	if err != nil {
+		return err
+	}
+	var req models.SetRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return apierror.FromBinding(err)
+	}
+	middleware.SetAuditDetail(c, "retention_days", req.RetentionDays)
+
+	user, err := h.userRepo.SetRetentionDays(c.Request.Context(), userID.String(), req.RetentionDays)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apierror.NotFound("User not found.")
+	}
+	if err != nil {
+		return apierror.Internal("Failed to set retention.", err)
+	}
+	h.logger.FromContext(c.Request.Context()).Info("User retention set by admin", zap.String("userID", user.ID))
+	c.JSON(http.StatusOK, models.NewAdminUser(user))
+	return nil
+}
+
+// ListUserAudio returns the metadata of every audio file of a user, including
+// files purged by retention.
+// GET /api/v1/admin/users/:id/audio
+func (h *AdminHandler) ListUserAudio(c *gin.Context) error {
+	ctx := c.Request.Context()
+	userID, err := auditedUserID(c)
+	if err != nil {
+		return err
+	}
+	if _, err := h.loadUser(ctx, userID); err != nil {
+		return err
+	}
+	files, err := h.audioRepo.ListAudioFilesByUserID(ctx, userID)
+	if err != nil {
+		return apierror.Internal("Failed to list audio files.", err)
+	}
+	if files == nil {
+		files = []models.AudioFile{}
+	}
+	c.JSON(http.StatusOK, models.AdminAudioList{AudioFiles: files})
+	return nil
+}
+
+// GetAudio returns the metadata of any user's audio file.
+// GET /api/v1/admin/audio/:id
+func (h *AdminHandler) GetAudio(c *gin.Context) error {
+	file, err := h.loadAudio(c)
+	if err != nil {
+		return err
+	}
+	c.JSON(http.StatusOK, file)
+	return nil
+}
+
+// DownloadAudio streams the stored audio of any user's file, so support can
+// listen to it without logging in as the user.
+// GET /api/v1/admin/audio/:id/content
+func (h *AdminHandler) DownloadAudio(c *gin.Context) error {
+	file, err := h.loadAudio(c)
+	if err != nil {
+		return err
+	}
+	if file.S3Key == "" {
+		return apierror.NotFound("The audio of this file was purged by retention.")
+	}
+	body, info, err := h.blobStore.Get(c.Request.Context(), file.S3Key, nil)
+	if errors.Is(err, storage.ErrNotFound) {
+		return apierror.NotFound("The stored audio of this file is missing.")
+	}
+	if err != nil {
+		return apierror.Internal("Failed to read audio file.", err)
+	}
+	defer body.Close()
+
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = info.ContentType
+	}
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(file.OriginalFilename)})
+	c.DataFromReader(http.StatusOK, info.SizeBytes, contentType, body, map[string]string{"Content-Disposition": disposition})
+	return nil
+}
+
+// ListAudit returns a page of the admin audit log, newest first, optionally
+// only the entries of one admin (actor_id), about one user (target_user_id)
+// or of one action.
+// GET /api/v1/admin/audit
+func (h *AdminHandler) ListAudit(c *gin.Context) error {
+	limit, offset, err := pagination(c)
+	if err != nil {
+		return err
+	}
+	filter := models.AuditFilter{Action: c.Query("action")}
+	if filter.ActorID, err = optionalUUID(c, "actor_id"); err != nil {
+		return err
+	}
+	if filter.TargetUserID, err = optionalUUID(c, "target_user_id"); err != nil {
+		return err
+	}
+
+	entries, total, err := h.auditRepo.ListAudit(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		return apierror.Internal("Failed to list audit log.", err)
+	}
+	c.JSON(http.StatusOK, models.AuditLogList{Entries: entries, Total: total, Limit: limit, Offset: offset})
+	return nil
+}
+
+// loadUser fetches a user, mapping a missing row to 404.
+func (h *AdminHandler) loadUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, err := h.userRepo.GetUserByID(ctx, id.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apierror.NotFound("User not found.")
+	}
+	if err != nil {
+		return nil, apierror.Internal("Failed to load user.", err)
+	}
+	return user, nil
+}
+
+// loadAudio fetches the audio file named by the :id parameter and records its owner as the audit target.
+func (h *AdminHandler) loadAudio(c *gin.Context) (*models.AudioFile, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return nil, apierror.InvalidRequest("Invalid audio file ID.")
+	}
+	file, err := h.audioRepo.GetAudioFileByID(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apierror.NotFound("Audio file not found.")
+	}
+	if err != nil {
+		return nil, apierror.Internal("Failed to load audio file.", err)
+	}
+	middleware.SetAuditTarget(c, file.UserID)
+	return file, nil
+}
+
+// auditedUserID parses the :id parameter as a user ID and records it as the audit target.
+func auditedUserID(c *gin.Context) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return uuid.Nil, apierror.InvalidRequest("Invalid user ID.")
+	}
+	middleware.SetAuditTarget(c, id)
+	return id, nil
+}
+
+// pagination reads the limit and offset query parameters.
+func pagination(c *gin.Context) (limit, offset int, err error) {
+	limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+	if err != nil || limit < 1 || limit > maxPageSize {
+		return 0, 0, apierror.InvalidRequest("limit must be between 1 and " + strconv.Itoa(maxPageSize) + ".")
+	}
+	offset, err = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		return 0, 0, apierror.InvalidRequest("offset must be a non-negative integer.")
+	}
+	return limit, offset, nil
+}
+
+// optionalUUID parses the named query parameter, returning nil if it is absent.
+func optionalUUID(c *gin.Context, name string) (*uuid.UUID, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, apierror.InvalidRequest(name + " must be a UUID.")
+	}
+	return &id, nil
+}
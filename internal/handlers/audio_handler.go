@@ -1,25 +1,36 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
-	"example.com/auth_service/internal/middleware"
+	"example.com/auth_service/internal/apierror"
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/internal/metrics"
 	"example.com/auth_service/internal/models"
-	"example.com/auth_service/internal/s3service" // Import the S3 service
-	"example.com/auth_service/pkg/logger"         // Import logger
+	"example.com/auth_service/internal/storage"
+	"example.com/auth_service/internal/tracing"
+	"example.com/auth_service/pkg/logger" // Import logger
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap" // For structured logging fields
 	// "example.com/auth_service/pkg/logger"
 	// "go.uber.org/zap"
 )
 
 const (
-	maxUploadSize = 10 * 1024 * 1024 // 10 MB
+	MaxUploadSize = 10 * 1024 * 1024 // 10 MB, for the whole request body
 	fileFormField = "audiofile"      // Name of the form field for the file
 )
 
@@ -32,51 +43,58 @@ var allowedAudioExtensions = map[string]bool{
 
 // AudioHandler handles HTTP requests related to audio files.
 type AudioHandler struct {
-	s3Service *s3service.S3Service
+	blobStore storage.BlobStore
 	audioRepo models.AudioRepository
+	usageRepo models.UsageRepository
+	txManager models.TxManager
+	quota     config.QuotaConfig
 	logger    *logger.Logger // Use our logger type
 }
 
 // NewAudioHandler creates a new AudioHandler.
-func NewAudioHandler(s3Svc *s3service.S3Service, audioRepo models.AudioRepository, appLogger *logger.Logger) *AudioHandler { // Accept logger
+func NewAudioHandler(blobStore storage.BlobStore, audioRepo models.AudioRepository, usageRepo models.UsageRepository, txManager models.TxManager, quota config.QuotaConfig, appLogger *logger.Logger) *AudioHandler { // Accept logger
 	return &AudioHandler{
-		s3Service: s3Svc,
+		blobStore: blobStore,
 		audioRepo: audioRepo,
+		usageRepo: usageRepo,
+		txManager: txManager,
+		quota:     quota,
 		logger:    appLogger, // Assign logger
 	}
 }
 
 // UploadAudioFile handles new audio file uploads.
 // POST /api/v1/audio/upload
-func (h *AudioHandler) UploadAudioFile(c *gin.Context) {
-	h.logger.Info("UploadAudioFile: Received request") // Use logger
+func (h *AudioHandler) UploadAudioFile(c *gin.Context) (err error) {
+	reqLogger := h.logger.FromContext(c.Request.Context())
+	reqLogger.Info("UploadAudioFile: Received request") // Use logger
+
+	start := time.Now()
+	var sizeBytes int64
+	var deduplicated bool
+	defer func() { observeUpload(err, deduplicated, sizeBytes, time.Since(start)) }()
 
 	// 1. Authentication & User ID retrieval
-	claims, exists := middleware.GetCurrentUserClaims(c)
-	if !exists || claims == nil {
-		h.logger.Warn("UploadAudioFile: User claims not found in context") // Use logger
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: user claims not found"})
-		return
-	}
-	userID, err := uuid.Parse(claims.UserID)
+	claims, userID, err := currentUser(c)
 	if err != nil {
-		h.logger.Error("UploadAudioFile: Invalid user ID in JWT claims", zap.String("user_id_str", claims.UserID), zap.Error(err)) // Use logger
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: invalid user ID in token"})
-		return
+		return err
 	}
 
 	// 2. File Processing (multipart/form-data)
-	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadSize)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxUploadSize)
+	_, parseSpan := tracing.Tracer().Start(c.Request.Context(), "parse multipart upload")
 	file, header, err := c.Request.FormFile(fileFormField)
+	tracing.End(parseSpan, err)
 	if err != nil {
-		if err.Error() == "http: request body too large" {
-			h.logger.Warn("UploadAudioFile: File size limit exceeded", zap.Error(err), zap.Int64("limit_bytes", maxUploadSize)) // Use logger
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("File size limit exceeded. Max size: %d MB", maxUploadSize/(1024*1024))})
-			return
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			reqLogger.Warn("UploadAudioFile: File size limit exceeded", zap.Error(err), zap.Int64("limit_bytes", MaxUploadSize)) // Use logger
+			return apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeAudioTooLarge,
+				fmt.Sprintf("File size limit exceeded. Max size: %d MB.", MaxUploadSize/(1024*1024))).
+				WithExtension("limit_bytes", MaxUploadSize)
 		}
-		h.logger.Error("UploadAudioFile: Error retrieving file from form", zap.Error(err)) // Use logger
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file upload request: " + err.Error()})
-		return
+		reqLogger.Warn("UploadAudioFile: Error retrieving file from form", zap.Error(err)) // Use logger
+		return apierror.InvalidRequest(fmt.Sprintf("Expected a multipart form with the file in the %q field.", fileFormField)).WithCause(err)
 	}
 	defer file.Close()
 
@@ -89,9 +107,10 @@ func (h *AudioHandler) UploadAudioFile(c *gin.Context) {
 	// ---> Start File Extension Validation <---
 	ext := strings.ToLower(filepath.Ext(originalFilename))
 	if !allowedAudioExtensions[ext] {
-		h.logger.Warn("UploadAudioFile: Invalid file extension", zap.String("filename", header.Filename), zap.String("extension", ext))
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid file format. Allowed formats: %v", getAllowedExtensionsList())})
-		return
+		reqLogger.Warn("UploadAudioFile: Invalid file extension", zap.String("filename", header.Filename), zap.String("extension", ext))
+		return apierror.New(http.StatusUnsupportedMediaType, apierror.CodeAudioFormat,
+			fmt.Sprintf("Invalid file format. Allowed formats: %v.", getAllowedExtensionsList())).
+			WithExtension("allowed_extensions", getAllowedExtensionsList())
 	}
 	// ---> End File Extension Validation <---
 
@@ -104,54 +123,166 @@ func (h *AudioHandler) UploadAudioFile(c *gin.Context) {
 		contentType = "application/octet-stream" // Default MIME type
 	}
 
-	// 3. Generate S3 Key
-	// Example: user_id/timestamp_nanoseconds/sanitized_filename.extension
-	baseFilename := strings.TrimSuffix(originalFilename, filepath.Ext(originalFilename)) // Use original extension for removing suffix
-	safeBaseFilename := strings.ReplaceAll(strings.ToLower(baseFilename), " ", "_")      // Basic sanitization
-	s3Key := fmt.Sprintf("%s/%d/%s%s", userID.String(), time.Now().UnixNano(), safeBaseFilename, ext)
+	ctx := c.Request.Context()
 
-	h.logger.Info("Attempting to upload to S3", zap.String("s3_key", s3Key), zap.String("content_type", contentType)) // Use logger
-
-	// 4. Upload to S3/MinIO
-	// The context from Gin (c.Request.Context()) should be used for S3 operations if they can be long-running.
-	fileURL, err := h.s3Service.UploadFile(c.Request.Context(), s3Key, file, contentType)
+	// 3. Hash the content
+	// The multipart parser has already spooled the file to memory or a temp file,
+	// so hash that copy and rewind it for the storage write.
+	_, hashSpan := tracing.Tracer().Start(ctx, "hash upload")
+	hasher := sha256.New()
+	sizeBytes, err = io.Copy(hasher, file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	hashSpan.SetAttributes(attribute.Int64("upload.size_bytes", sizeBytes))
+	tracing.End(hashSpan, err)
 	if err != nil {
-		h.logger.Error("Failed to upload file to S3", zap.String("s3_key", s3Key), zap.Error(err))
-		// Return standard error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file to storage"})
-		return
+		return apierror.Internal("Failed to process uploaded file.", err)
 	}
+	contentSHA256 := hex.EncodeToString(hasher.Sum(nil))
+	s3Key := blobKey(contentSHA256)
 
-	// 5. Save Metadata to PostgreSQL
+	// 4. Enforce the user's quota before anything is written to storage
+	if _, err := h.usageRepo.ReserveUpload(ctx, userID, sizeBytes, h.quota.LimitsFor(claims.Role), h.quota.Period); err != nil {
+		var quotaErr *models.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			reqLogger.Warn("UploadAudioFile: Quota exceeded", zap.String("userID", userID.String()), zap.String("kind", string(quotaErr.Kind)), zap.Int64("limit", quotaErr.Limit))
+			return quotaExceeded(quotaErr)
+		}
+		return apierror.Internal("Failed to check upload quota.", err)
+	}
+
+	// 5. Take a reference on the content-addressed blob, then make sure the object exists.
+	// Holding the reference first guarantees nobody deletes the object underneath us.
+	blob := &models.AudioBlob{
+		ContentSHA256: contentSHA256,
+		S3Key:         s3Key,
+		SizeBytes:     sizeBytes,
+		ContentType:   contentType,
+	}
+	if err := h.audioRepo.AcquireBlob(ctx, blob); err != nil {
+		h.abortUpload(ctx, userID, sizeBytes, "")
+		return apierror.Internal("Failed to save audio file metadata.", fmt.Errorf("acquire blob %s: %w", contentSHA256, err))
+	}
+
+	deduplicated = true
+	var fileURL string
+	if _, err := h.blobStore.Head(ctx, s3Key); errors.Is(err, storage.ErrNotFound) {
+		reqLogger.Info("Attempting to upload to S3", zap.String("s3_key", s3Key), zap.String("content_type", contentType)) // Use logger
+		deduplicated = false
+		fileURL, err = h.blobStore.Put(ctx, s3Key, file, contentType)
+		if err != nil {
+			h.abortUpload(ctx, userID, sizeBytes, contentSHA256)
+			return apierror.Internal("Failed to upload file to storage.", fmt.Errorf("put %s: %w", s3Key, err))
+		}
+	} else if err != nil {
+		h.abortUpload(ctx, userID, sizeBytes, contentSHA256)
+		return apierror.Internal("Failed to upload file to storage.", fmt.Errorf("head %s: %w", s3Key, err))
+	} else {
+		reqLogger.Info("Identical content already stored, skipping upload", zap.String("s3_key", s3Key), zap.Int("ref_count", blob.RefCount))
+	}
+
+	// 6. Save Metadata to PostgreSQL
 	audioFileMetadata := &models.AudioFile{
 		ID:               uuid.New(), // New ID for this audio file record
 		UserID:           userID,
 		S3Key:            s3Key,
 		OriginalFilename: originalFilename,
 		ContentType:      contentType,
-		SizeBytes:        header.Size,
+		SizeBytes:        sizeBytes,
+		ContentSHA256:    contentSHA256,
 		UploadedAt:       time.Now(),
 	}
 
-	if err := h.audioRepo.SaveAudioFile(c.Request.Context(), audioFileMetadata); err != nil {
-		h.logger.Error("Failed to save audio metadata to DB", zap.String("s3_key", s3Key), zap.Error(err)) // Use logger
-		// Consider a cleanup strategy: if DB save fails, should we delete from S3?
-		// For now, we just return an error. This might leave an orphaned file in S3.
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save audio file metadata"})
-		return
+	if err := h.audioRepo.SaveAudioFile(ctx, audioFileMetadata); err != nil {
+		h.abortUpload(ctx, userID, sizeBytes, contentSHA256)
+		return apierror.Internal("Failed to save audio file metadata.", fmt.Errorf("save metadata for %s: %w", s3Key, err))
 	}
 
-	h.logger.Info("Audio file uploaded and metadata saved",
+	// Detection results are not reused here yet: this service has no detection
+	// pipeline, so there is nothing to look up by (content_sha256, model version).
+
+	reqLogger.Info("Audio file uploaded and metadata saved",
 		zap.String("userID", userID.String()),
 		zap.String("s3_key", s3Key),
+		zap.Bool("deduplicated", deduplicated),
 		zap.String("audioFileID", audioFileMetadata.ID.String())) // Use logger
 
 	c.JSON(http.StatusCreated, models.UploadAudioResponse{
-		ID:      audioFileMetadata.ID,
-		S3Key:   s3Key,
-		Message: "Audio file uploaded successfully",
-		FileURL: fileURL, // This URL might be empty if S3 endpoint resolution failed in s3service
+		ID:           audioFileMetadata.ID,
+		S3Key:        s3Key,
+		Message:      "Audio file uploaded successfully",
+		FileURL:      fileURL, // Empty when the content was deduplicated
+		Deduplicated: deduplicated,
 	})
+	return nil
+}
+
+// observeUpload records the upload metrics, deriving the outcome from the
+// handler's error: client errors are rejections, anything else a failure.
+func observeUpload(err error, deduplicated bool, sizeBytes int64, elapsed time.Duration) {
+	outcome := metrics.UploadStored
+	var apiErr *apierror.Error
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Status < http.StatusInternalServerError:
+		outcome = metrics.UploadRejected
+	case err != nil:
+		outcome = metrics.UploadFailed
+	case deduplicated:
+		outcome = metrics.UploadDeduplicated
+	}
+	metrics.ObserveUpload(outcome, sizeBytes, elapsed)
+}
+
+// abortUpload undoes a failed upload in one transaction: it returns the
+// reserved quota and, when contentSHA256 is set, drops the blob reference,
+// deleting the object if nobody else uses it. Failures are logged; the
+// reconciler cleans up leftovers.
+func (h *AudioHandler) abortUpload(ctx context.Context, userID uuid.UUID, sizeBytes int64, contentSHA256 string) {
+	ctx = context.WithoutCancel(ctx) // Clean up even if the client has gone away
+	err := h.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if contentSHA256 != "" {
+			err := h.audioRepo.ReleaseBlob(ctx, contentSHA256, func(s3Key string) error {
+				return h.blobStore.Delete(ctx, s3Key)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return h.usageRepo.CancelUpload(ctx, userID, sizeBytes)
+	})
+	if err != nil {
+		h.logger.FromContext(ctx).Error("Failed to roll back aborted upload", zap.String("userID", userID.String()), zap.String("sha256", contentSHA256), zap.Error(err))
+	}
+}
+
+// quotaExceeded maps a quota error to 413 for storage limits and 429 with
+// Retry-After for the per-period upload limit.
+func quotaExceeded(quotaErr *models.QuotaExceededError) *apierror.Error {
+	var e *apierror.Error
+	switch quotaErr.Kind {
+	case models.QuotaUploads:
+		retryAfter := int(math.Ceil(quotaErr.RetryAfter.Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		e = apierror.New(http.StatusTooManyRequests, apierror.CodeUploadRateLimited,
+			fmt.Sprintf("Upload limit reached: %d uploads per period. Try again later.", quotaErr.Limit)).
+			WithHeader("Retry-After", strconv.Itoa(retryAfter))
+	case models.QuotaFiles:
+		e = apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeStorageQuota,
+			fmt.Sprintf("Storage quota exceeded: at most %d files.", quotaErr.Limit))
+	default:
+		e = apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeStorageQuota,
+			fmt.Sprintf("Storage quota exceeded: at most %d MB.", quotaErr.Limit/(1024*1024)))
+	}
+	return e.WithExtension("quota", quotaErr.Kind).WithExtension("limit", quotaErr.Limit).WithCause(quotaErr)
+}
+
+// blobKey returns the content-addressed storage key for a SHA-256 digest.
+// The two-character fan-out keeps listings of any single prefix small.
+func blobKey(contentSHA256 string) string {
+	return fmt.Sprintf("blobs/sha256/%s/%s", contentSHA256[:2], contentSHA256)
 }
 
 // Helper function to get list of allowed extensions for error message
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"example.com/auth_service/internal/health"
+	"example.com/auth_service/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// HealthHandler serves the liveness and readiness probes.
+type HealthHandler struct {
+	checker *health.Checker
+	logger  *logger.Logger
+}
+
+// NewHealthHandler creates a new HealthHandler.
+func NewHealthHandler(checker *health.Checker, appLogger *logger.Logger) *HealthHandler {
+	return &HealthHandler{
+		checker: checker,
+		logger:  appLogger,
+	}
+}
+
+// Liveness reports that the process is up. It checks no dependencies, so a
+// database outage does not get the instance restarted.
+// GET /healthz
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Readiness reports whether the instance can serve uploads, with the result
+// of each dependency check. It returns 503 if any dependency is unavailable.
+// GET /readyz
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	report := h.checker.Check(c.Request.Context())
+	if report.Status != health.StatusOK {
+		h.logger.FromContext(c.Request.Context()).Warn("Readiness check failed", zap.Any("components", report.Components))
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"example.com/auth_service/internal/apierror"
+	"example.com/auth_service/internal/auth"
+	"example.com/auth_service/internal/models"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetProfile returns the caller's account.
+// GET /api/v1/users/me
+func (h *UserHandler) GetProfile(c *gin.Context) error {
+	_, userID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+	user, err := h.loadUser(c.Request.Context(), userID.String())
+	if err != nil {
+		return err
+	}
+	c.JSON(http.StatusOK, user)
+	return nil
+}
+
+// UpdateProfile changes the caller's username and/or email. A new email is
+// not applied until the link sent to it has been followed; until then it is
+// reported as pending_email.
+// PATCH /api/v1/users/me
+func (h *UserHandler) UpdateProfile(c *gin.Context) error {
+	ctx := c.Request.Context()
+	reqLogger := h.logger.FromContext(ctx)
+	_, userID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reqLogger.Warn("Invalid profile update request", zap.Error(err))
+		return apierror.FromBinding(err)
+	}
+	if req.Username == nil && req.Email == nil {
+		return apierror.InvalidRequest("Nothing to update. Set username and/or email.")
+	}
+
+	user, err := h.loadUser(ctx, userID.String())
+	if err != nil {
+		return err
+	}
+
+	if req.Username != nil && *req.Username != user.Username {
+		user, err = h.userRepository.UpdateUsername(ctx, user.ID, *req.Username)
+		if errors.Is(err, models.ErrUsernameTaken) {
+			return apierror.New(http.StatusConflict, apierror.CodeUsernameTaken, "This username is already taken.")
+		}
+		if err != nil {
+			return apierror.Internal("Failed to update profile.", err)
+		}
+		reqLogger.Info("Username changed", zap.String("userID", user.ID))
+	}
+
+	if req.Email != nil && *req.Email != user.Email {
+		// Checked here so the user hears about it now; the unique constraint decides at confirmation.
+		existing, err := h.userRepository.GetUserByEmail(ctx, *req.Email)
+		if err == nil && existing.ID != user.ID {
+			return apierror.New(http.StatusConflict, apierror.CodeEmailTaken, "A user with this email already exists.")
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return apierror.Internal("Failed to update profile.", err)
+		}
+
+		token, tokenHash, err := auth.NewOpaqueToken()
+		if err != nil {
+			return apierror.Internal("Failed to update profile.", err)
+		}
+		user, err = h.userRepository.RequestEmailChange(ctx, user.ID, *req.Email, tokenHash, time.Now().Add(h.email.VerificationTTL))
+		if err != nil {
+			return apierror.Internal("Failed to update profile.", err)
+		}
+		if err := h.sendEmailVerification(ctx, *req.Email, token); err != nil {
+			return apierror.Internal("Failed to send the verification email.", err)
+		}
+		reqLogger.Info("Email change requested, verification sent", zap.String("userID", user.ID))
+	}
+
+	c.JSON(http.StatusOK, user)
+	return nil
+}
+
+// VerifyEmail applies a pending email change. It needs no token, only the
+// verification token sent to the new address.
+// POST /api/v1/users/verify-email
+func (h *UserHandler) VerifyEmail(c *gin.Context) error {
+	reqLogger := h.logger.FromContext(c.Request.Context())
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reqLogger.Warn("Invalid email verification request", zap.Error(err))
+		return apierror.FromBinding(err)
+	}
+
+	user, err := h.userRepository.ConfirmEmailChange(c.Request.Context(), auth.HashOpaqueToken(req.Token))
+	if errors.Is(err, models.ErrVerificationInvalid) {
+		reqLogger.Warn("Invalid or expired email verification token")
+		return apierror.New(http.StatusBadRequest, apierror.CodeVerificationInvalid,
+			"This verification link is invalid or has expired. Request the email change again.")
+	}
+	if errors.Is(err, models.ErrEmailTaken) {
+		return apierror.New(http.StatusConflict, apierror.CodeEmailTaken, "A user with this email already exists.")
+	}
+	if err != nil {
+		return apierror.Internal("Failed to verify email.", err)
+	}
+
+	reqLogger.Info("Email changed", zap.String("userID", user.ID))
+	c.JSON(http.StatusOK, user)
+	return nil
+}
+
+// ChangePassword replaces the caller's password after checking the current
+// one. Every token issued before the change stops working, so other sessions
+// are logged out; the response carries a new token for this one.
+// POST /api/v1/users/me/password
+func (h *UserHandler) ChangePassword(c *gin.Context) error {
+	ctx := c.Request.Context()
+	reqLogger := h.logger.FromContext(ctx)
+	_, userID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reqLogger.Warn("Invalid password change request", zap.Error(err))
+		return apierror.FromBinding(err)
+	}
+
+	user, err := h.loadUser(ctx, userID.String())
+	if err != nil {
+		return err
+	}
+	if !auth.CheckPasswordHash(req.CurrentPassword, user.Password) {
+		reqLogger.Warn("Password change with incorrect current password", zap.String("userID", user.ID))
+		return apierror.ValidationFailed(apierror.FieldError{Field: "current_password", Message: "is incorrect"})
+	}
+
+	hashedPassword, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		return apierror.Internal("Failed to change password.", err)
+	}
+	// Revokes every earlier token. The token issued below is not before the
+	// cut-off, so it stays valid.
+	if err := h.userRepository.UpdatePassword(ctx, user.ID, hashedPassword, auth.SessionCutoff()); err != nil {
+		return apierror.Internal("Failed to change password.", err)
+	}
+
+	token, err := h.authService.GenerateJWT(user.ID, user.Email, user.Role)
+	if err != nil {
+		return apierror.Internal("Failed to change password.", err)
+	}
+
+	reqLogger.Info("Password changed, other sessions revoked", zap.String("userID", user.ID))
+	c.JSON(http.StatusOK, models.ChangePasswordResponse{Token: token})
+	return nil
+}
+
+// loadUser fetches a user, mapping a missing row to 404.
+func (h *UserHandler) loadUser(ctx context.Context, id string) (*models.User, error) {
+	user, err := h.userRepository.GetUserByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apierror.NotFound("User not found.")
+	}
+	if err != nil {
+		return nil, apierror.Internal("Failed to load user.", err)
+	}
+	return user, nil
+}
+
+// sendEmailVerification mails the confirmation link for an email change to the new address.
+func (h *UserHandler) sendEmailVerification(ctx context.Context, to, token string) error {
+	link, err := url.Parse(h.email.VerificationURL)
+	if err != nil {
+		return err
+	}
+	q := link.Query()
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+
+	body := fmt.Sprintf("Confirm your new email address by opening this link:\n\n%s\n\nThe link expires in %s. If you did not ask for this change, ignore this email.\n",
+		link.String(), h.email.VerificationTTL)
+	return h.mailer.Send(ctx, to, "Confirm your new email address", body)
+}
@@ -6,7 +6,11 @@ import (
 	"net/http"
 	"time"
 
+	"example.com/auth_service/internal/apierror"
 	"example.com/auth_service/internal/auth"
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/internal/mail"
+	"example.com/auth_service/internal/middleware"
 	"example.com/auth_service/internal/models"
 	"example.com/auth_service/pkg/logger" // Import logger
 	"github.com/gin-gonic/gin"
@@ -15,52 +19,48 @@ import (
 	// "example.com/auth_service/pkg/logger" // Assuming you have a logger package
 )
 
+// errInvalidCredentials is returned for both unknown emails and wrong passwords,
+// so the response does not reveal which accounts exist.
+var errInvalidCredentials = apierror.New(http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid email or password.")
+
 // UserHandler handles HTTP requests related to users.
 type UserHandler struct {
 	authService    *auth.AuthService
 	userRepository models.UserRepository
+	usageRepo      models.UsageRepository
+	quota          config.QuotaConfig
+	email          config.EmailConfig
+	mailer         mail.Sender
 	logger         *logger.Logger // Use our logger type
 }
 
 // NewUserHandler creates a new UserHandler.
-func NewUserHandler(authService *auth.AuthService, userRepo models.UserRepository, appLogger *logger.Logger) *UserHandler { // Accept logger
+func NewUserHandler(authService *auth.AuthService, userRepo models.UserRepository, usageRepo models.UsageRepository, quota config.QuotaConfig, email config.EmailConfig, mailer mail.Sender, appLogger *logger.Logger) *UserHandler { // Accept logger
 	return &UserHandler{
 		authService:    authService,
 		userRepository: userRepo,
+		usageRepo:      usageRepo,
+		quota:          quota,
+		email:          email,
+		mailer:         mailer,
 		logger:         appLogger, // Assign logger
 	}
 }
 
 // RegisterUser handles new user registration.
 // POST /api/v1/users/register
-func (h *UserHandler) RegisterUser(c *gin.Context) {
+func (h *UserHandler) RegisterUser(c *gin.Context) error {
+	reqLogger := h.logger.FromContext(c.Request.Context())
 	var req models.RegistrationRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Error("Invalid registration request format", zap.Error(err)) // Use logger
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
-		return
-	}
-
-	// Check if user already exists
-	_, err := h.userRepository.GetUserByEmail(req.Email)
-	if err == nil { // If err is nil, user found
-		h.logger.Warn("Registration attempt for existing email", zap.String("email", req.Email)) // Use logger
-		c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists"})
-		return
-	}
-	// We expect an error (e.g., sql.ErrNoRows), so if it's a different error, it's a problem.
-	if !errors.Is(err, sql.ErrNoRows) { // Check specific error
-		h.logger.Error("Error checking user existence during registration", zap.Error(err)) // Use logger
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process registration"})
-		return
+		reqLogger.Warn("Invalid registration request", zap.Error(err))
+		return apierror.FromBinding(err)
 	}
 
 	hashedPassword, err := auth.HashPassword(req.Password)
 	if err != nil {
-		h.logger.Error("Failed to hash password during registration", zap.Error(err)) // Use logger
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process registration"})
-		return
+		return apierror.Internal("Failed to process registration.", err)
 	}
 
 	newUser := &models.User{
@@ -68,72 +68,147 @@ func (h *UserHandler) RegisterUser(c *gin.Context) {
 		Username:  req.Username,
 		Email:     req.Email,
 		Password:  hashedPassword,
+		Role:      models.RoleUser,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	if err := h.userRepository.CreateUser(newUser); err != nil {
-		h.logger.Error("Failed to create user in DB during registration", zap.Error(err), zap.String("email", newUser.Email)) // Use logger
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
-		return
+	// The unique constraints decide duplicates, so concurrent registrations cannot both succeed.
+	if err := h.userRepository.CreateUser(c.Request.Context(), newUser); err != nil {
+		if errors.Is(err, models.ErrEmailTaken) {
+			reqLogger.Warn("Registration attempt for existing email", zap.String("email", req.Email)) // Use logger
+			return apierror.New(http.StatusConflict, apierror.CodeEmailTaken, "A user with this email already exists.")
+		}
+		if errors.Is(err, models.ErrUsernameTaken) {
+			reqLogger.Warn("Registration attempt for existing username", zap.String("username", req.Username))
+			return apierror.New(http.StatusConflict, apierror.CodeUsernameTaken, "This username is already taken.")
+		}
+		return apierror.Internal("Failed to register user.", err)
 	}
 
-	h.logger.Info("User registered successfully", zap.String("email", newUser.Email), zap.String("userID", newUser.ID)) // Use logger
+	reqLogger.Info("User registered successfully", zap.String("email", newUser.Email), zap.String("userID", newUser.ID)) // Use logger
 
 	// Return a simplified user object or just a success message
 	// For security, newUser.Password is already omitted by json:"-" in the model
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "User registered successfully",
-		"user_id": newUser.ID,
+	c.JSON(http.StatusCreated, models.RegistrationResponse{
+		Message: "User registered successfully",
+		UserID:  newUser.ID,
 	})
+	return nil
 }
 
 // LoginUser handles user login and JWT generation.
 // POST /api/v1/users/login
-func (h *UserHandler) LoginUser(c *gin.Context) {
+func (h *UserHandler) LoginUser(c *gin.Context) error {
+	reqLogger := h.logger.FromContext(c.Request.Context())
 	var req models.LoginRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Error("Invalid login request format", zap.Error(err)) // Use logger
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
-		return
+		reqLogger.Warn("Invalid login request", zap.Error(err))
+		return apierror.FromBinding(err)
 	}
 
-	user, err := h.userRepository.GetUserByEmail(req.Email)
+	user, err := h.userRepository.GetUserByEmail(c.Request.Context(), req.Email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) { // Check specific error
-			h.logger.Warn("Login attempt for non-existent email", zap.String("email", req.Email)) // Use logger
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
-			return
+			reqLogger.Warn("Login attempt for non-existent email", zap.String("email", req.Email)) // Use logger
+			return errInvalidCredentials
 		}
-		h.logger.Error("Error retrieving user for login", zap.Error(err), zap.String("email", req.Email)) // Use logger
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})                      // Generic error for security
-		return
+		return apierror.Internal("Failed to login.", err)
 	}
 
 	if !auth.CheckPasswordHash(req.Password, user.Password) {
-		h.logger.Warn("Incorrect password attempt", zap.String("email", req.Email)) // Use logger
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
-		return
+		reqLogger.Warn("Incorrect password attempt", zap.String("email", req.Email)) // Use logger
+		return errInvalidCredentials
+	}
+	if user.DeletedAt != nil {
+		reqLogger.Warn("Login attempt for account being erased", zap.String("userID", user.ID))
+		return errInvalidCredentials
+	}
+	if user.DisabledAt != nil {
+		reqLogger.Warn("Login attempt for disabled account", zap.String("userID", user.ID))
+		return apierror.New(http.StatusForbidden, apierror.CodeAccountDisabled, "This account has been disabled. Contact support.")
 	}
 
-	token, err := h.authService.GenerateJWT(user.ID, user.Email)
+	token, err := h.authService.GenerateJWT(user.ID, user.Email, user.Role)
 	if err != nil {
-		h.logger.Error("Failed to generate JWT during login", zap.Error(err), zap.String("user_id", user.ID)) // Use logger
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to login"})
-		return
+		return apierror.Internal("Failed to login.", err)
 	}
 
-	h.logger.Info("User logged in successfully", zap.String("email", user.Email), zap.String("userID", user.ID)) // Use logger
+	reqLogger.Info("User logged in successfully", zap.String("email", user.Email), zap.String("userID", user.ID)) // Use logger
 	c.JSON(http.StatusOK, models.LoginResponse{
 		Token: token,
 		User: models.User{ // Return a safe representation of the user
 			ID:       user.ID,
 			Username: user.Username,
 			Email:    user.Email,
+			Role:     user.Role,
 			// Password field is omitted due to `json:"-"` tag
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
+			CreatedAt:    user.CreatedAt,
+			UpdatedAt:    user.UpdatedAt,
+			PendingEmail: user.PendingEmail,
 		},
 	})
+	return nil
+}
+
+// GetUsage returns the caller's storage usage, quota limits and what remains.
+// GET /api/v1/users/me/usage
+func (h *UserHandler) GetUsage(c *gin.Context) error {
+	claims, userID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	usage, err := h.usageRepo.GetUsage(c.Request.Context(), userID)
+	if err != nil {
+		return apierror.Internal("Failed to load usage.", err)
+	}
+
+	// An elapsed period has not been reset in the DB yet; report it as it will be on the next upload.
+	periodEnd := usage.PeriodStart.Add(h.quota.Period)
+	if !time.Now().Before(periodEnd) {
+		usage.PeriodStart = time.Now()
+		usage.UploadsInPeriod = 0
+		periodEnd = usage.PeriodStart.Add(h.quota.Period)
+	}
+
+	role := claims.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+	limits := h.quota.LimitsFor(role)
+	c.JSON(http.StatusOK, models.UsageResponse{
+		Role:      role,
+		Usage:     *usage,
+		PeriodEnd: periodEnd,
+		Limits:    limits,
+		Remaining: models.QuotaLimits{
+			MaxBytes:            remaining(limits.MaxBytes, usage.BytesStored),
+			MaxFiles:            int(remaining(int64(limits.MaxFiles), int64(usage.FileCount))),
+			MaxUploadsPerPeriod: int(remaining(int64(limits.MaxUploadsPerPeriod), int64(usage.UploadsInPeriod))),
+		},
+	})
+	return nil
+}
+
+// remaining returns limit-used, floored at zero. An unlimited (zero) limit stays zero.
+func remaining(limit, used int64) int64 {
+	if limit == 0 || used >= limit {
+		return 0
+	}
+	return limit - used
+}
+
+// currentUser returns the authenticated caller's claims and parsed user ID.
+func currentUser(c *gin.Context) (*auth.Claims, uuid.UUID, error) {
+	claims, exists := middleware.GetCurrentUserClaims(c)
+	if !exists || claims == nil {
+		return nil, uuid.Nil, apierror.Unauthorized("User claims not found.")
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, uuid.Nil, apierror.Unauthorized("Invalid user ID in token.").WithCause(err)
+	}
+	return claims, userID, nil
 }
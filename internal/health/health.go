@@ -0,0 +1,110 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Component statuses reported by a Checker.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// CheckFunc probes one dependency. It may return details to include in the
+// report, such as pool statistics, whether or not the check failed.
+type CheckFunc func(ctx context.Context) (details any, err error)
+
+// Result is the outcome of one check.
+type Result struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs int64     `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+	Details   any       `json:"details,omitempty"`
+}
+
+// Report is the readiness of every registered dependency.
+type Report struct {
+	Status     string            `json:"status"`
+	Components map[string]Result `json:"components"`
+}
+
+// check is a registered dependency with its cached result.
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+
+	mu     sync.Mutex // Serialises runs so concurrent probes share one result
+	result Result
+}
+
+// Checker runs dependency checks for the readiness endpoint. Each check has
+// its own timeout, and results are cached for cacheTTL so that frequent
+// probes from several orchestrators do not hammer the dependencies.
+type Checker struct {
+	cacheTTL time.Duration
+	checks   []*check
+}
+
+// NewChecker creates a Checker that caches results for cacheTTL.
+func NewChecker(cacheTTL time.Duration) *Checker {
+	return &Checker{cacheTTL: cacheTTL}
+}
+
+// Register adds a dependency check. It is not safe to call once Check is in use.
+func (c *Checker) Register(name string, timeout time.Duration, fn CheckFunc) {
+	c.checks = append(c.checks, &check{name: name, timeout: timeout, fn: fn})
+}
+
+// Check runs every check concurrently, reusing cached results that are still
+// fresh. The report is ok only if every component is ok.
+func (c *Checker) Check(ctx context.Context) Report {
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, chk := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = chk.run(ctx, c.cacheTTL)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Components: make(map[string]Result, len(c.checks))}
+	for i, chk := range c.checks {
+		report.Components[chk.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
+
+// run returns the cached result if it is younger than ttl, or runs the check.
+func (chk *check) run(ctx context.Context, ttl time.Duration) Result {
+	chk.mu.Lock()
+	defer chk.mu.Unlock()
+	if !chk.result.CheckedAt.IsZero() && time.Since(chk.result.CheckedAt) < ttl {
+		return chk.result
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, chk.timeout)
+	defer cancel()
+	start := time.Now()
+	details, err := chk.fn(checkCtx)
+	res := Result{
+		Status:    StatusOK,
+		LatencyMs: time.Since(start).Milliseconds(),
+		CheckedAt: start,
+		Details:   details,
+	}
+	if err != nil {
+		res.Status = StatusUnavailable
+		res.Error = err.Error()
+	}
+	chk.result = res
+	return res
+}
@@ -0,0 +1,37 @@
+// Package mail sends email to users.
+package mail
+
+import (
+	"context"
+
+	"example.com/auth_service/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Sender delivers a plain-text message to one recipient.
+type Sender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// LogSender logs messages instead of delivering them. It is meant for local
+// development. The body is not logged, since it carries secrets such as
+// verification links; use an SMTP catcher to read it.
+type LogSender struct {
+	logger *logger.Logger
+}
+
+var _ Sender = (*LogSender)(nil)
+
+// NewLogSender creates a LogSender.
+func NewLogSender(appLogger *logger.Logger) *LogSender {
+	return &LogSender{logger: appLogger}
+}
+
+// Send logs the recipient and subject at info level.
+func (s *LogSender) Send(ctx context.Context, to, subject, body string) error {
+	s.logger.FromContext(ctx).Info("Email (not delivered, log sender)",
+		zap.String("to", to),
+		zap.String("subject", subject),
+		zap.Int("body_bytes", len(body)))
+	return nil
+}
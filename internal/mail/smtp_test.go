@@ -0,0 +1,116 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"example.com/auth_service/internal/config"
+)
+
+// smtpServer accepts one session without TLS or auth and returns what the
+// client sent: the envelope and the message.
+func smtpServer(t *testing.T) (addr string, session <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	done := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		var lines []string
+		tp.PrintfLine("220 localhost ready")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				break
+			}
+			cmd := strings.ToUpper(strings.Fields(line + " ")[0])
+			switch cmd {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "MAIL", "RCPT":
+				lines = append(lines, line)
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 Go ahead")
+				data, _ := io.ReadAll(tp.DotReader())
+				lines = append(lines, string(data))
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 Bye")
+				done <- lines
+				return
+			default:
+				tp.PrintfLine("502 Not implemented")
+			}
+		}
+		done <- lines
+	}()
+	return ln.Addr().String(), done
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	addr, session := smtpServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+	s, err := NewSMTPSender(config.EmailConfig{From: "Audio <no-reply@example.com>", SMTPHost: host, SMTPPort: port})
+	if err != nil {
+		t.Fatalf("NewSMTPSender: %v", err)
+	}
+
+	body := "Open this link:\n\nhttps://example.com/verify-email?token=abc\n"
+	if err := s.Send(context.Background(), "new@example.com", "Confirm\r\nBcc: evil@example.com", body); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	lines := <-session
+	if len(lines) != 3 {
+		t.Fatalf("session = %q, want MAIL, RCPT and DATA", lines)
+	}
+	if lines[0] != "MAIL FROM:<no-reply@example.com>" {
+		t.Errorf("MAIL = %q", lines[0])
+	}
+	if lines[1] != "RCPT TO:<new@example.com>" {
+		t.Errorf("RCPT = %q", lines[1])
+	}
+
+	msg, err := netmail.ReadMessage(bufio.NewReader(strings.NewReader(lines[2])))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	if got := msg.Header.Get("To"); got != "<new@example.com>" {
+		t.Errorf("To = %q", got)
+	}
+	if got := msg.Header.Get("Bcc"); got != "" {
+		t.Errorf("subject injected a Bcc header: %q", got)
+	}
+	decoded, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if got := strings.ReplaceAll(string(decoded), "\r\n", "\n"); got != body {
+		t.Errorf("body = %q, want %q", got, body)
+	}
+}
+
+func TestSMTPSenderRejectsBadRecipient(t *testing.T) {
+	s, err := NewSMTPSender(config.EmailConfig{From: "no-reply@example.com", SMTPHost: "127.0.0.1", SMTPPort: "1"})
+	if err != nil {
+		t.Fatalf("NewSMTPSender: %v", err)
+	}
+	if err := s.Send(context.Background(), "a@example.com\r\nRCPT TO:<b@example.com>", "Subject", "body"); err == nil {
+		t.Error("Send accepted a recipient with a line break")
+	}
+}
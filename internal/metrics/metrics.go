@@ -0,0 +1,115 @@
+// Package metrics defines the Prometheus metrics exported on /metrics and
+// the helpers that record them.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "auth_service"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	uploadBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_size_bytes",
+		Help:      "Size of uploaded audio files.",
+		Buckets:   prometheus.ExponentialBuckets(64*1024, 4, 8), // 64 KiB .. 1 GiB
+	})
+
+	uploadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_duration_seconds",
+		Help:      "Time to process an upload, from receiving the file to saving its metadata, by outcome.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12), // 50ms .. ~100s
+	}, []string{"outcome"})
+
+	storageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Blob store operation latency by backend and operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"backend", "operation"})
+
+	storageErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_errors_total",
+		Help:      "Failed blob store operations by backend and operation. Missing objects are not errors.",
+	}, []string{"backend", "operation"})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query latency by target (primary, replica or tx) and kind (exec, get, select, query).",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"target", "kind"})
+)
+
+// Upload outcomes recorded by ObserveUpload.
+const (
+	UploadStored       = "stored"
+	UploadDeduplicated = "deduplicated"
+	UploadRejected     = "rejected" // Invalid file or quota exceeded
+	UploadFailed       = "failed"
+)
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware records request count and latency for every request. Routes
+// are labelled by their template (e.g. /api/v1/audio/:id) to keep cardinality bounded.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveUpload records one upload attempt. sizeBytes is only recorded for
+// uploads that were stored.
+func ObserveUpload(outcome string, sizeBytes int64, elapsed time.Duration) {
+	uploadDuration.WithLabelValues(outcome).Observe(elapsed.Seconds())
+	if outcome == UploadStored || outcome == UploadDeduplicated {
+		uploadBytes.Observe(float64(sizeBytes))
+	}
+}
+
+// ObserveDBQuery records the latency of one database query.
+func ObserveDBQuery(target, kind string, elapsed time.Duration) {
+	dbQueryDuration.WithLabelValues(target, kind).Observe(elapsed.Seconds())
+}
+
+// RegisterDBStats exports the connection pool statistics of db, labelled with name.
+func RegisterDBStats(db *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
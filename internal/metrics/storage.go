@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"example.com/auth_service/internal/storage"
+)
+
+// instrumentedStore records latency and errors for every call to the wrapped store.
+type instrumentedStore struct {
+	next    storage.BlobStore
+	backend string
+}
+
+// InstrumentBlobStore wraps store so that each operation is measured, labelled with backend.
+func InstrumentBlobStore(store storage.BlobStore, backend string) storage.BlobStore {
+	return &instrumentedStore{next: store, backend: backend}
+}
+
+// observe records one operation. storage.ErrNotFound is an answer, not a failure.
+func (s *instrumentedStore) observe(operation string, start time.Time, err error) {
+	storageDuration.WithLabelValues(s.backend, operation).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		storageErrors.WithLabelValues(s.backend, operation).Inc()
+	}
+}
+
+func (s *instrumentedStore) Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error) {
+	start := time.Now()
+	url, err := s.next.Put(ctx, key, body, contentType)
+	s.observe("put", start, err)
+	return url, err
+}
+
+// Get measures the time to open the object, not to read its body.
+func (s *instrumentedStore) Get(ctx context.Context, key string, rng *storage.ByteRange) (io.ReadCloser, *storage.ObjectInfo, error) {
+	start := time.Now()
+	body, info, err := s.next.Get(ctx, key, rng)
+	s.observe("get", start, err)
+	return body, info, err
+}
+
+func (s *instrumentedStore) Head(ctx context.Context, key string) (*storage.ObjectInfo, error) {
+	start := time.Now()
+	info, err := s.next.Head(ctx, key)
+	s.observe("head", start, err)
+	return info, err
+}
+
+func (s *instrumentedStore) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.next.Delete(ctx, key)
+	s.observe("delete", start, err)
+	return err
+}
+
+// List measures the whole walk, including the time spent in fn.
+func (s *instrumentedStore) List(ctx context.Context, prefix string, fn func(storage.ObjectInfo) error) error {
+	start := time.Now()
+	err := s.next.List(ctx, prefix, fn)
+	s.observe("list", start, err)
+	return err
+}
+
+func (s *instrumentedStore) Presign(ctx context.Context, key string, expires time.Duration) (string, error) {
+	start := time.Now()
+	url, err := s.next.Presign(ctx, key, expires)
+	s.observe("presign", start, err)
+	return url, err
+}
+
+func (s *instrumentedStore) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := s.next.Ping(ctx)
+	s.observe("ping", start, err)
+	return err
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"example.com/auth_service/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AccessLog writes one structured log line per request, replacing gin's text
+// logger. It must run after RequestID so the line carries request_id, user_id and route.
+// Server errors are logged at error level and client errors at warn.
+func AccessLog(appLogger *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		fields := []interface{}{
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("client_ip", c.ClientIP()),
+			zap.Int("response_bytes", c.Writer.Size()),
+			zap.String("user_agent", c.Request.UserAgent()),
+		}
+		if errs := c.Errors.ByType(gin.ErrorTypePrivate).String(); errs != "" {
+			fields = append(fields, zap.String("errors", errs))
+		}
+
+		reqLogger := appLogger.FromContext(c.Request.Context())
+		switch {
+		case status >= http.StatusInternalServerError:
+			reqLogger.Error("HTTP request", fields...)
+		case status >= http.StatusBadRequest:
+			reqLogger.Warn("HTTP request", fields...)
+		default:
+			reqLogger.Info("HTTP request", fields...)
+		}
+	}
+}
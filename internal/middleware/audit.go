@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+
+	"example.com/auth_service/internal/apierror"
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// auditContextKey stores the *auditRecord of the current request.
+const auditContextKey = "adminAudit"
+
+// auditRecord collects what handlers add to the request's audit entry.
+type auditRecord struct {
+	target  *uuid.UUID
+	details map[string]any
+}
+
+// Audit writes an entry to the admin audit log for every request, whatever
+// its outcome: the caller, action, the user acted on (see SetAuditTarget),
+// the response status, the request ID, and the route parameters and query
+// along with any details the handler added (see SetAuditDetail).
+//
+// The entry is written after the handler, so it records the outcome. It is
+// written even if the client has gone away; a failure to write it is logged.
+// Use it after AuthMiddleware.
+func Audit(repo models.AuditRepository, action string, appLogger *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetCurrentUserClaims(c)
+		if !ok {
+			apierror.Abort(c, apierror.Unauthorized("User claims not found."))
+			return
+		}
+		actorID, err := uuid.Parse(claims.UserID)
+		if err != nil {
+			apierror.Abort(c, apierror.Unauthorized("Invalid user ID in token.").WithCause(err))
+			return
+		}
+
+		record := &auditRecord{details: make(map[string]any)}
+		if len(c.Params) > 0 {
+			params := make(map[string]string, len(c.Params))
+			for _, p := range c.Params {
+				params[p.Key] = p.Value
+			}
+			record.details["params"] = params
+		}
+		if query := c.Request.URL.Query(); len(query) > 0 {
+			record.details["query"] = query
+		}
+		c.Set(auditContextKey, record)
+
+		c.Next()
+
+		// Errors returned by the handler are rendered further out, so take their status.
+		status := c.Writer.Status()
+		if last := c.Errors.Last(); last != nil && !c.Writer.Written() {
+			status = apierror.StatusOf(last.Err)
+		}
+
+		ctx := c.Request.Context()
+		reqLogger := appLogger.FromContext(ctx).With(zap.String("action", action), zap.String("actor_id", actorID.String()))
+		details, err := json.Marshal(record.details)
+		if err != nil {
+			reqLogger.Error("Failed to encode admin audit details", zap.Error(err))
+			details = []byte(`{}`)
+		}
+		entry := &models.AuditEntry{
+			ID:           uuid.New(),
+			ActorID:      actorID,
+			Action:       action,
+			TargetUserID: record.target,
+			Status:       status,
+			Details:      details,
+		}
+		if requestID := logger.RequestID(ctx); requestID != "" {
+			entry.RequestID = &requestID
+		}
+		if err := repo.RecordAudit(context.WithoutCancel(ctx), entry); err != nil {
+			reqLogger.Error("Failed to record admin audit entry", zap.Error(err), zap.Int("status", status))
+		}
+	}
+}
+
+// SetAuditTarget records the user an admin request acts on. Set it as soon as
+// the user is known, so the entry has it even if the request then fails.
+func SetAuditTarget(c *gin.Context, userID uuid.UUID) {
+	if record := auditRecordOf(c); record != nil {
+		record.target = &userID
+	}
+}
+
+// SetAuditDetail adds a field to the details of the request's audit entry.
+func SetAuditDetail(c *gin.Context, key string, value any) {
+	if record := auditRecordOf(c); record != nil {
+		record.details[key] = value
+	}
+}
+
+func auditRecordOf(c *gin.Context) *auditRecord {
+	v, _ := c.Get(auditContextKey)
+	record, _ := v.(*auditRecord)
+	return record
+}
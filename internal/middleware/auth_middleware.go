@@ -1,9 +1,11 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
+	"example.com/auth_service/internal/apierror"
 	"example.com/auth_service/internal/auth"
 	"example.com/auth_service/pkg/logger" // Import logger
 	"github.com/gin-gonic/gin"
@@ -20,32 +22,67 @@ const (
 // AuthMiddleware creates a Gin middleware for JWT authentication.
 func AuthMiddleware(authService *auth.AuthService, appLogger *logger.Logger) gin.HandlerFunc { // Accept logger
 	return func(c *gin.Context) {
+		reqLogger := appLogger.FromContext(c.Request.Context())
 		authHeader := c.GetHeader(authorizationHeaderKey)
 		if authHeader == "" {
-			appLogger.Warn("Authorization header missing") // Use logger
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			reqLogger.Warn("Authorization header missing") // Use logger
+			apierror.Abort(c, apierror.Unauthorized("Authorization header required."))
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || strings.ToLower(parts[0]) != authorizationTypeBearer {
-			appLogger.Warn("Invalid authorization header format", zap.String("header", authHeader)) // Use logger
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format. Use Bearer token."})
+			reqLogger.Warn("Invalid authorization header format", zap.String("header", authHeader)) // Use logger
+			apierror.Abort(c, apierror.Unauthorized("Invalid authorization header format. Use Bearer token."))
 			return
 		}
 
 		tokenString := parts[1]
 		claims, err := authService.ValidateJWT(tokenString)
 		if err != nil {
-			appLogger.Error("Invalid JWT token", zap.Error(err)) // Use logger
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			reqLogger.Warn("Invalid JWT token", zap.Error(err)) // Use logger
+			apierror.Abort(c, apierror.Unauthorized("Invalid or expired token.").WithCause(err))
+			return
+		}
+		if err := authService.CheckSession(c.Request.Context(), claims); err != nil {
+			if errors.Is(err, auth.ErrAccountDisabled) {
+				reqLogger.Warn("JWT token of disabled user", zap.String("userID", claims.UserID))
+				apierror.Abort(c, apierror.New(http.StatusForbidden, apierror.CodeAccountDisabled, "This account has been disabled.").WithCause(err))
+				return
+			}
+			if errors.Is(err, auth.ErrSessionRevoked) {
+				reqLogger.Warn("Revoked JWT token", zap.String("userID", claims.UserID))
+				apierror.Abort(c, apierror.Unauthorized("This session has ended. Log in again.").WithCause(err))
+				return
+			}
+			apierror.Abort(c, apierror.Internal("Failed to check the session.", err))
 			return
 		}
 
 		// Set user claims in context for downstream handlers
 		c.Set(userContextKey, claims)
-		appLogger.Info("User authenticated via JWT", zap.String("userID", claims.UserID), zap.String("email", claims.Email)) // Use logger
+		logger.SetUserID(c.Request.Context(), claims.UserID)                                                                 // Adds user_id to request-scoped logs
+		reqLogger.Info("User authenticated via JWT", zap.String("userID", claims.UserID), zap.String("email", claims.Email)) // Use logger
+
+		c.Next()
+	}
+}
 
+// RequireRole rejects authenticated users without the given role with 403.
+// Use it after AuthMiddleware, which refreshes the role from the database.
+func RequireRole(role string, appLogger *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetCurrentUserClaims(c)
+		if !ok {
+			apierror.Abort(c, apierror.Unauthorized("User claims not found."))
+			return
+		}
+		if claims.Role != role {
+			appLogger.FromContext(c.Request.Context()).Warn("Request without required role",
+				zap.String("userID", claims.UserID), zap.String("role", claims.Role), zap.String("required_role", role))
+			apierror.Abort(c, apierror.Forbidden("This endpoint requires the "+role+" role."))
+			return
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"strings"
+
+	"example.com/auth_service/internal/config"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS applies the configured cross-origin policy. Origins are matched
+// exactly, except that "https://*.example.com" matches any subdomain of
+// example.com (but not example.com itself) over https.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	corsConfig := cors.Config{
+		AllowMethods:     cfg.AllowMethods,
+		AllowHeaders:     cfg.AllowHeaders,
+		ExposeHeaders:    cfg.ExposeHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	}
+	for _, origin := range cfg.AllowOrigins {
+		if origin == "*" {
+			corsConfig.AllowAllOrigins = true
+			return cors.New(corsConfig)
+		}
+	}
+	corsConfig.AllowOriginFunc = originMatcher(cfg.AllowOrigins)
+	return cors.New(corsConfig)
+}
+
+// wildcardOrigin matches any origin with this scheme whose host ends in suffix.
+type wildcardOrigin struct {
+	scheme string // "https://"
+	suffix string // ".example.com", including any port
+}
+
+// originMatcher returns a func reporting whether an Origin header matches one of patterns.
+func originMatcher(patterns []string) func(origin string) bool {
+	exact := make(map[string]bool)
+	var wildcards []wildcardOrigin
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSuffix(p, "/"))
+		if scheme, host, ok := strings.Cut(p, "://*."); ok {
+			wildcards = append(wildcards, wildcardOrigin{scheme: scheme + "://", suffix: "." + host})
+			continue
+		}
+		exact[p] = true
+	}
+	return func(origin string) bool {
+		origin = strings.ToLower(origin)
+		if exact[origin] {
+			return true
+		}
+		for _, w := range wildcards {
+			host, ok := strings.CutPrefix(origin, w.scheme)
+			if ok && len(host) > len(w.suffix) && strings.HasSuffix(host, w.suffix) && !strings.ContainsAny(host, "/@") {
+				return true
+			}
+		}
+		return false
+	}
+}
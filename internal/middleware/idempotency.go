@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"example.com/auth_service/internal/apierror"
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client's key for a mutating request.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader marks a response replayed from an earlier request.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength matches idempotency_keys.idempotency_key.
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency makes retries of a request with the same Idempotency-Key header
+// safe. The first request runs and, if it succeeds, its response is stored
+// for cfg.TTL and replayed for later requests with the key. A key reused for a
+// different request (method, route or body) gets 409 IDEMPOTENCY_KEY_REUSED,
+// and one whose first request is still running gets 409 IDEMPOTENCY_KEY_IN_USE.
+// Failed requests release the key, so they can be retried with it.
+//
+// Keys are per user, so use it after AuthMiddleware. Bodies larger than
+// maxBody are passed through without a key, for the handler to reject.
+// Requests without the header are not affected.
+func Idempotency(repo models.IdempotencyRepository, cfg config.IdempotencyConfig, maxBody int64, appLogger *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength || !isPrintableASCII(key) {
+			apierror.Abort(c, apierror.InvalidRequest(fmt.Sprintf("%s must be 1 to %d printable ASCII characters.", IdempotencyKeyHeader, maxIdempotencyKeyLength)))
+			return
+		}
+		claims, ok := GetCurrentUserClaims(c)
+		if !ok {
+			c.Next()
+			return
+		}
+		userID, err := uuid.Parse(claims.UserID)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		fingerprint, ok, err := fingerprintRequest(c, maxBody)
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidRequest("Failed to read the request body.").WithCause(err))
+			return
+		}
+		if !ok {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		reqLogger := appLogger.FromContext(ctx).With(zap.String("idempotency_key", key))
+		record, reserved, err := repo.Reserve(ctx, userID, key, fingerprint, cfg.TTL, cfg.LockTimeout)
+		if err != nil {
+			apierror.Abort(c, apierror.Internal("Failed to check the idempotency key.", err))
+			return
+		}
+		if !reserved {
+			replay(c, record, fingerprint)
+			return
+		}
+
+		// This request holds the key. The outcome is stored even if the client
+		// has gone away meanwhile, since its retry is what the key is for.
+		// If it outlives cfg.LockTimeout, a retry may take the key over; the
+		// reservation's LockedAt keeps this request from touching the retry's.
+		storeCtx := context.WithoutCancel(ctx)
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := repo.Release(storeCtx, userID, key, record.LockedAt); err != nil {
+				reqLogger.Error("Failed to release idempotency key", zap.Error(err))
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		c.Writer = recorder.ResponseWriter
+
+		status := recorder.Status()
+		if len(c.Errors) > 0 || !recorder.Written() || status < 200 || status >= 300 {
+			return // Not a success; let the client retry
+		}
+		if err := repo.Complete(storeCtx, userID, key, record.LockedAt, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			reqLogger.Error("Failed to store idempotent response; a retry will run again", zap.Error(err))
+			return
+		}
+		completed = true
+	}
+}
+
+// replay answers a request whose key was already reserved.
+func replay(c *gin.Context, record *models.IdempotencyRecord, fingerprint string) {
+	switch {
+	case record.Fingerprint != fingerprint:
+		apierror.Abort(c, apierror.New(http.StatusConflict, apierror.CodeIdempotencyReused,
+			"This Idempotency-Key was already used for a different request."))
+	case record.CompletedAt == nil || record.StatusCode == nil:
+		apierror.Abort(c, apierror.New(http.StatusConflict, apierror.CodeIdempotencyInUse,
+			"A request with this Idempotency-Key is still being processed.").
+			WithHeader("Retry-After", "1"))
+	default:
+		contentType := ""
+		if record.ContentType != nil {
+			contentType = *record.ContentType
+		}
+		c.Header(idempotentReplayedHeader, "true")
+		c.Data(*record.StatusCode, contentType, record.Body)
+		c.Abort()
+	}
+}
+
+// fingerprintRequest hashes the method, route and body, and leaves the body
+// readable for the handler. For multipart bodies the parts are hashed rather
+// than the raw bytes, because clients pick a new boundary on every retry.
+// It returns false if the body is larger than maxBody.
+func fingerprintRequest(c *gin.Context, maxBody int64) (string, bool, error) {
+	body := c.Request.Body
+	if body == nil || body == http.NoBody {
+		body = io.NopCloser(bytes.NewReader(nil))
+	}
+	buf, err := io.ReadAll(io.LimitReader(body, maxBody+1))
+	if err != nil {
+		return "", false, err
+	}
+	if int64(len(buf)) > maxBody {
+		c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(buf), body), body}
+		return "", false, nil
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(buf))
+
+	h := sha256.New()
+	writeField(h, c.Request.Method)
+	writeField(h, c.FullPath())
+	mediaType, params, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil {
+		writeField(h, c.GetHeader("Content-Type"))
+		writeField(h, string(buf))
+		return hex.EncodeToString(h.Sum(nil)), true, nil
+	}
+	writeField(h, mediaType)
+	if !strings.HasPrefix(mediaType, "multipart/") || hashParts(h, buf, params["boundary"]) != nil {
+		writeField(h, string(buf))
+	}
+	return hex.EncodeToString(h.Sum(nil)), true, nil
+}
+
+// hashParts hashes each part's name, filename, content type and content.
+func hashParts(h hash.Hash, body []byte, boundary string) error {
+	if boundary == "" {
+		return errors.New("multipart body without boundary")
+	}
+	parts := sha256.New()
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		writeField(parts, part.FormName())
+		writeField(parts, part.FileName())
+		writeField(parts, part.Header.Get("Content-Type"))
+		content := sha256.New()
+		if _, err := io.Copy(content, part); err != nil {
+			return err
+		}
+		writeField(parts, hex.EncodeToString(content.Sum(nil)))
+	}
+	writeField(h, hex.EncodeToString(parts.Sum(nil)))
+	return nil
+}
+
+// writeField writes s length-prefixed, so adjacent fields cannot run together.
+func writeField(h hash.Hash, s string) {
+	fmt.Fprintf(h, "%d:%s;", len(s), s)
+}
+
+// isPrintableASCII reports whether s is non-empty visible ASCII (spaces allowed).
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// readCloser reads from one reader and closes another.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// responseRecorder copies the response body as it is written.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/auth_service/internal/apierror"
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/internal/ratelimit"
+	"example.com/auth_service/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// stubLimiter returns a fixed result and records the keys it was asked for.
+type stubLimiter struct {
+	res  ratelimit.Result
+	err  error
+	keys []string
+}
+
+func (l *stubLimiter) Allow(_ context.Context, key string, _ config.RateLimit) (ratelimit.Result, error) {
+	l.keys = append(l.keys, key)
+	return l.res, l.err
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	limit := config.RateLimit{Burst: 3, Period: time.Minute}
+	tests := []struct {
+		name        string
+		res         ratelimit.Result
+		err         error
+		limit       config.RateLimit
+		wantStatus  int
+		wantHeaders map[string]string // "" means the header must be absent
+	}{
+		{
+			name:       "allowed",
+			res:        ratelimit.Result{Allowed: true, Limit: 3, Remaining: 2, Reset: 20 * time.Second},
+			limit:      limit,
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"RateLimit-Limit":     "3",
+				"RateLimit-Remaining": "2",
+				"RateLimit-Reset":     "20",
+				"RateLimit-Policy":    "3;w=60",
+				"Retry-After":         "",
+			},
+		},
+		{
+			name:       "reset rounds up",
+			res:        ratelimit.Result{Allowed: true, Limit: 3, Remaining: 1, Reset: 20*time.Second + time.Millisecond},
+			limit:      limit,
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"RateLimit-Remaining": "1",
+				"RateLimit-Reset":     "21",
+			},
+		},
+		{
+			name:       "denied",
+			res:        ratelimit.Result{Limit: 3, RetryAfter: 1500 * time.Millisecond, Reset: time.Minute},
+			limit:      limit,
+			wantStatus: http.StatusTooManyRequests,
+			wantHeaders: map[string]string{
+				"RateLimit-Limit":     "3",
+				"RateLimit-Remaining": "0",
+				"RateLimit-Reset":     "60",
+				"RateLimit-Policy":    "3;w=60",
+				"Retry-After":         "2",
+			},
+		},
+		{
+			name:        "denied retries after at least a second",
+			res:         ratelimit.Result{Limit: 3, RetryAfter: time.Millisecond, Reset: time.Minute},
+			limit:       limit,
+			wantStatus:  http.StatusTooManyRequests,
+			wantHeaders: map[string]string{"Retry-After": "1"},
+		},
+		{
+			name:       "limiter failure lets the request through",
+			err:        errors.New("redis down"),
+			limit:      limit,
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"RateLimit-Limit": "",
+				"Retry-After":     "",
+			},
+		},
+		{
+			name:        "disabled",
+			res:         ratelimit.Result{Limit: 3},
+			limit:       config.RateLimit{},
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{"RateLimit-Limit": ""},
+		},
+	}
+
+	appLogger, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatalf("logger: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := &stubLimiter{res: tt.res, err: tt.err}
+			r := gin.New()
+			r.Use(apierror.Middleware(appLogger))
+			r.GET("/", RateLimit(limiter, "login", tt.limit, ByClientIP, appLogger), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			for name, want := range tt.wantHeaders {
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if tt.limit.Burst == 0 {
+				if len(limiter.keys) != 0 {
+					t.Errorf("disabled limit consulted the limiter for %q", limiter.keys)
+				}
+			} else if len(limiter.keys) != 1 || limiter.keys[0] != "ratelimit:login:ip:192.0.2.1" {
+				t.Errorf("limiter keys = %q, want [ratelimit:login:ip:192.0.2.1]", limiter.keys)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"example.com/auth_service/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// readPrimaryCookie is set after a mutation so the client's next reads see it.
+	readPrimaryCookie = "read_primary"
+	// readPrimaryHeader lets clients that do not keep cookies ask for primary reads.
+	readPrimaryHeader = "X-Read-Your-Writes"
+)
+
+// ReadYourWrites routes repository reads to the primary database for mutating
+// requests and, for window afterwards, for requests from the same client.
+// Without it a client could read from a replica that has not yet applied its own write.
+func ReadYourWrites(window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mutation := isMutation(c.Request.Method)
+		if mutation || c.GetHeader(readPrimaryHeader) != "" || hasCookie(c, readPrimaryCookie) {
+			c.Request = c.Request.WithContext(database.WithPrimary(c.Request.Context()))
+		}
+		// Set before the handler runs; headers cannot change once it writes the body.
+		if mutation && window > 0 {
+			http.SetCookie(c.Writer, &http.Cookie{
+				Name:     readPrimaryCookie,
+				Value:    "1",
+				Path:     "/",
+				MaxAge:   int(window.Round(time.Second).Seconds()),
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		c.Next()
+	}
+}
+
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+func hasCookie(c *gin.Context, name string) bool {
+	_, err := c.Cookie(name)
+	return err == nil
+}
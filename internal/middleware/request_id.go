@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"example.com/auth_service/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// RequestIDHeader carries the request ID in both directions.
+	RequestIDHeader = "X-Request-ID"
+	// maxRequestIDLength bounds IDs accepted from clients.
+	maxRequestIDLength = 128
+)
+
+// RequestID accepts the caller's X-Request-ID, or generates one, and returns it
+// in the response. The ID is stored in the request context for
+// logger.FromContext, together with the matched route template.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, id)
+
+		ctx := logger.NewRequestContext(c.Request.Context(), id, c.FullPath())
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", id))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// validRequestID accepts non-empty IDs of printable ASCII, so a client cannot
+// inject control characters or huge values into the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
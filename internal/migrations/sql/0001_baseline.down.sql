@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS audio_files;
+DROP TRIGGER IF EXISTS trigger_users_updated_at ON users;
+DROP TABLE IF EXISTS users;
+DROP FUNCTION IF EXISTS update_updated_at_column();
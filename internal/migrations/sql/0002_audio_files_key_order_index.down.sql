@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_audio_files_s3_key_c;
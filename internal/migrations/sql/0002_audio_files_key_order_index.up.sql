@@ -0,0 +1,2 @@
+-- Byte-order index so the reconciler can page through keys in the same order as S3 ListObjectsV2
+CREATE INDEX IF NOT EXISTS idx_audio_files_s3_key_c ON audio_files(s3_key COLLATE "C");
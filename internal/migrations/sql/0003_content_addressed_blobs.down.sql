@@ -0,0 +1,5 @@
+-- Restoring the UNIQUE constraint fails while deduplicated rows share a key
+DROP INDEX IF EXISTS idx_audio_files_content_sha256;
+DROP TABLE IF EXISTS audio_blobs;
+ALTER TABLE audio_files DROP COLUMN IF EXISTS content_sha256;
+ALTER TABLE audio_files ADD CONSTRAINT audio_files_s3_key_key UNIQUE (s3_key);
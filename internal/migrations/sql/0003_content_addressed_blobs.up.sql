@@ -0,0 +1,14 @@
+-- Identical uploads share one stored object, so s3_key is no longer unique per row
+ALTER TABLE audio_files DROP CONSTRAINT IF EXISTS audio_files_s3_key_key;
+ALTER TABLE audio_files ADD COLUMN IF NOT EXISTS content_sha256 CHAR(64); -- NULL for files stored before deduplication
+
+CREATE TABLE IF NOT EXISTS audio_blobs (
+    content_sha256 CHAR(64) PRIMARY KEY,
+    s3_key VARCHAR(512) NOT NULL UNIQUE,
+    size_bytes BIGINT NOT NULL,
+    content_type VARCHAR(100),
+    ref_count INTEGER NOT NULL CHECK (ref_count >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audio_files_content_sha256 ON audio_files(content_sha256);
@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user'; -- Selects the quota limits applied to the user
+
+-- Per-user storage and upload accounting used for quota enforcement
+CREATE TABLE IF NOT EXISTS user_usage (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    bytes_stored BIGINT NOT NULL DEFAULT 0,
+    file_count INTEGER NOT NULL DEFAULT 0,
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    uploads_in_period INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Seed usage for files uploaded before quotas existed
+INSERT INTO user_usage (user_id, bytes_stored, file_count)
+SELECT user_id, COALESCE(SUM(size_bytes), 0), COUNT(*) FROM audio_files GROUP BY user_id
+ON CONFLICT (user_id) DO NOTHING;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS audio_purges;
+DROP INDEX IF EXISTS idx_audio_files_uploaded_at;
+ALTER TABLE audio_files DROP COLUMN IF EXISTS purged_at;
+ALTER TABLE users DROP COLUMN IF EXISTS retention_days;
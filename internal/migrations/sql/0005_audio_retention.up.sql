@@ -0,0 +1,20 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS retention_days INTEGER; -- NULL uses the global default, 0 keeps audio forever
+ALTER TABLE audio_files ADD COLUMN IF NOT EXISTS purged_at TIMESTAMP WITH TIME ZONE; -- Set when the row was kept anonymised
+
+CREATE INDEX IF NOT EXISTS idx_audio_files_uploaded_at ON audio_files(uploaded_at) WHERE purged_at IS NULL;
+
+-- Audit trail of audio removed by the retention sweeper
+CREATE TABLE IF NOT EXISTS audio_purges (
+    id UUID PRIMARY KEY,
+    audio_file_id UUID NOT NULL, -- No FK: the audio_files row is usually gone
+    user_id UUID NOT NULL,
+    s3_key VARCHAR(512) NOT NULL,
+    content_sha256 CHAR(64),
+    size_bytes BIGINT,
+    uploaded_at TIMESTAMP WITH TIME ZONE,
+    purged_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reason VARCHAR(50) NOT NULL,
+    metadata_kept BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audio_purges_user_id ON audio_purges(user_id);
@@ -0,0 +1,15 @@
+-- Responses remembered for Idempotency-Key headers, so retried requests are not executed twice
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_fingerprint CHAR(64) NOT NULL, -- SHA-256 of method, route and body
+    locked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE, -- NULL while the first request is still running
+    status_code INTEGER,
+    content_type VARCHAR(255),
+    response_body BYTEA,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS email_verifications;
+ALTER TABLE users DROP COLUMN IF EXISTS sessions_valid_after;
+ALTER TABLE users DROP COLUMN IF EXISTS pending_email;
@@ -0,0 +1,15 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE; -- Set when erasure is requested; the user can no longer log in
+
+-- Account erasure jobs. Rows are kept after completion as a record that the account was erased.
+CREATE TABLE IF NOT EXISTS account_deletions (
+    user_id UUID PRIMARY KEY, -- No FK: the users row is deleted by the job
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP WITH TIME ZONE, -- Set while a worker holds the job
+    completed_at TIMESTAMP WITH TIME ZONE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    files_deleted INTEGER NOT NULL DEFAULT 0,
+    objects_deleted INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_deletions_pending ON account_deletions(requested_at) WHERE completed_at IS NULL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS admin_audit_log;
+DROP INDEX IF EXISTS idx_users_created_at;
+ALTER TABLE users DROP COLUMN IF EXISTS disabled_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS disabled_at;
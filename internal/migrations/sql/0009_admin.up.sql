@@ -0,0 +1,20 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP WITH TIME ZONE; -- Set by an admin; the user cannot log in or use existing tokens
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
+
+-- Every request to the admin API
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id UUID PRIMARY KEY,
+    actor_id UUID NOT NULL, -- No FKs: entries outlive both the admin and the target
+    action VARCHAR(50) NOT NULL,
+    target_user_id UUID,
+    status INTEGER NOT NULL, -- HTTP status of the response
+    request_id VARCHAR(128),
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_created_at ON admin_audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_target_user_id ON admin_audit_log(target_user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_actor_id ON admin_audit_log(actor_id, created_at);
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_audio_files_s3_key_c ON audio_files(s3_key COLLATE "C");
+DROP INDEX IF EXISTS idx_audio_files_s3_key_c_id;
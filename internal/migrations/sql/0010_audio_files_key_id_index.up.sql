@@ -0,0 +1,3 @@
+-- Several rows can share an s3_key since deduplication, so the reconciler pages on (s3_key, id)
+CREATE INDEX IF NOT EXISTS idx_audio_files_s3_key_c_id ON audio_files(s3_key COLLATE "C", id);
+DROP INDEX IF EXISTS idx_audio_files_s3_key_c;
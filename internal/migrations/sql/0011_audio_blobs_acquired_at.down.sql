@@ -0,0 +1 @@
+ALTER TABLE audio_blobs DROP COLUMN IF EXISTS acquired_at;
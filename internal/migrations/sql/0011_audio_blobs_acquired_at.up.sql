@@ -0,0 +1,2 @@
+-- When the blob was last referenced. The reconciler leaves younger references alone, since their upload may still be running
+ALTER TABLE audio_blobs ADD COLUMN IF NOT EXISTS acquired_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
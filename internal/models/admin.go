@@ -0,0 +1,134 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+)
+
+// User states an admin can filter by.
+const (
+	UserStatusActive   = "active"
+	UserStatusDisabled = "disabled"
+	UserStatusDeleted  = "deleted" // Erasure requested
+)
+
+// UserFilter selects users for UserRepository.SearchUsers. Empty fields match every user.
+type UserFilter struct {
+	Query  string // Substring of the username or email, or an exact user ID
+	Role   string
+	Status string // One of the UserStatus constants
+}
+
+// AdminUser is a user as shown to admins, including the account state users do not see.
+type AdminUser struct {
+	ID             string     `json:"id"`
+	Username       string     `json:"username"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	PendingEmail   *string    `json:"pending_email,omitempty"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason *string    `json:"disabled_reason,omitempty"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	RetentionDays  *int       `json:"retention_days,omitempty"` // Absent when the global default applies
+}
+
+// NewAdminUser returns the admin view of u.
+func NewAdminUser(u *User) AdminUser {
+	return AdminUser{
+		ID:             u.ID,
+		Username:       u.Username,
+		Email:          u.Email,
+		Role:           u.Role,
+		CreatedAt:      u.CreatedAt,
+		UpdatedAt:      u.UpdatedAt,
+		PendingEmail:   u.PendingEmail,
+		DisabledAt:     u.DisabledAt,
+		DisabledReason: u.DisabledReason,
+		DeletedAt:      u.DeletedAt,
+		RetentionDays:  u.RetentionDays,
+	}
+}
+
+// AdminUserList is returned by GET /api/v1/admin/users.
+type AdminUserList struct {
+	Users  []AdminUser `json:"users"`
+	Total  int         `json:"total"` // Matching users across all pages
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// AdminUserDetail is returned by GET /api/v1/admin/users/{id}.
+type AdminUserDetail struct {
+	User   AdminUser   `json:"user"`
+	Usage  Usage       `json:"usage"`
+	Limits QuotaLimits `json:"limits"` // For the user's role
+}
+
+// DisableUserRequest is the body of POST /api/v1/admin/users/{id}/disable.
+type DisableUserRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// SetRetentionRequest is the body of PUT /api/v1/admin/users/{id}/retention.
+// A null or absent retention_days restores the global default.
+type SetRetentionRequest struct {
+	RetentionDays *int `json:"retention_days" binding:"omitempty,min=0,max=36500"` // 0 keeps audio forever
+}
+
+// AdminAudioList is returned by GET /api/v1/admin/users/{id}/audio.
+type AdminAudioList struct {
+	AudioFiles []AudioFile `json:"audio_files"` // Oldest first, including files purged by retention
+}
+
+// Admin actions recorded in the audit log.
+const (
+	AuditUserSearch    = "user.search"
+	AuditUserGet       = "user.get"
+	AuditUserDisable   = "user.disable"
+	AuditUserEnable    = "user.enable"
+	AuditQuotaReset    = "user.quota_reset"
+	AuditUserRetention = "user.retention"
+	AuditAudioList     = "audio.list"
+	AuditAudioGet      = "audio.get"
+	AuditAudioDownload = "audio.download"
+	AuditLogRead       = "audit.list"
+)
+
+// AuditEntry records one request to the admin API.
+type AuditEntry struct {
+	ID           uuid.UUID      `db:"id" json:"id"`
+	ActorID      uuid.UUID      `db:"actor_id" json:"actor_id"`
+	Action       string         `db:"action" json:"action"`
+	TargetUserID *uuid.UUID     `db:"target_user_id" json:"target_user_id,omitempty"`
+	Status       int            `db:"status" json:"status"` // HTTP status of the response
+	RequestID    *string        `db:"request_id" json:"request_id,omitempty"`
+	Details      types.JSONText `db:"details" json:"details"` // Route parameters, query and action-specific fields
+	CreatedAt    time.Time      `db:"created_at" json:"created_at"`
+}
+
+// AuditFilter selects entries for AuditRepository.ListAudit. Empty fields match every entry.
+type AuditFilter struct {
+	ActorID      *uuid.UUID
+	TargetUserID *uuid.UUID
+	Action       string
+}
+
+// AuditLogList is returned by GET /api/v1/admin/audit.
+type AuditLogList struct {
+	Entries []AuditEntry `json:"entries"` // Newest first
+	Total   int          `json:"total"`
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+}
+
+// AuditRepository stores the admin audit log. Entries are never updated or deleted.
+type AuditRepository interface {
+	RecordAudit(ctx context.Context, entry *AuditEntry) error
+	// ListAudit returns a page of entries matching filter, newest first, and the number of matches.
+	ListAudit(ctx context.Context, filter AuditFilter, limit, offset int) ([]AuditEntry, int, error)
+}
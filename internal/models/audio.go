@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,7 +16,34 @@ type AudioFile struct {
 	OriginalFilename string    `db:"original_filename" json:"original_filename"`
 	ContentType      string    `db:"content_type" json:"content_type,omitempty"`
 	SizeBytes        int64     `db:"size_bytes" json:"size_bytes,omitempty"`
+	ContentSHA256    string    `db:"content_sha256" json:"content_sha256,omitempty"` // Empty for files uploaded before deduplication
 	UploadedAt       time.Time `db:"uploaded_at" json:"uploaded_at"`
+	// PurgedAt is set when the audio was removed by retention but the row was kept, anonymised.
+	PurgedAt *time.Time `db:"purged_at" json:"purged_at,omitempty"`
+}
+
+// AudioBlob is a content-addressed stored object shared by every AudioFile with the same SHA-256.
+type AudioBlob struct {
+	ContentSHA256 string    `db:"content_sha256" json:"content_sha256"`
+	S3Key         string    `db:"s3_key" json:"s3_key"`
+	SizeBytes     int64     `db:"size_bytes" json:"size_bytes"`
+	ContentType   string    `db:"content_type" json:"content_type"`
+	RefCount      int       `db:"ref_count" json:"ref_count"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+}
+
+// AudioPurge is the audit record written for every audio file removed by the retention sweeper.
+type AudioPurge struct {
+	ID            uuid.UUID `db:"id" json:"id"`
+	AudioFileID   uuid.UUID `db:"audio_file_id" json:"audio_file_id"`
+	UserID        uuid.UUID `db:"user_id" json:"user_id"`
+	S3Key         string    `db:"s3_key" json:"s3_key"`
+	ContentSHA256 string    `db:"content_sha256" json:"content_sha256,omitempty"`
+	SizeBytes     int64     `db:"size_bytes" json:"size_bytes"`
+	UploadedAt    time.Time `db:"uploaded_at" json:"uploaded_at"`
+	PurgedAt      time.Time `db:"purged_at" json:"purged_at"`
+	Reason        string    `db:"reason" json:"reason"`
+	MetadataKept  bool      `db:"metadata_kept" json:"metadata_kept"`
 }
 
 // UploadAudioResponse defines the structure for a successful audio upload response.
@@ -24,11 +52,42 @@ type UploadAudioResponse struct {
 	S3Key   string    `json:"s3_key"`
 	Message string    `json:"message"`
 	FileURL string    `json:"file_url,omitempty"` // Optional: URL to access the file
+	// Deduplicated is true when identical content was already stored and no new object was written.
+	Deduplicated bool `json:"deduplicated"`
 }
 
 // AudioRepository defines the interface for audio file data operations.
 type AudioRepository interface {
 	SaveAudioFile(ctx context.Context, audioFile *AudioFile) error
 	GetAudioFileByID(ctx context.Context, id uuid.UUID) (*AudioFile, error)
-	// ListAudioFilesByUserID(ctx context.Context, userID uuid.UUID) ([]AudioFile, error) // Optional
+	// ListAudioFilesAfterKey pages through unpurged files in (s3_key, id) order,
+	// returning up to limit files after (afterKey, afterID).
+	ListAudioFilesAfterKey(ctx context.Context, afterKey string, afterID uuid.UUID, limit int) ([]AudioFile, error)
+	// ListAudioFilesByUserID returns all of a user's files, including purged ones.
+	ListAudioFilesByUserID(ctx context.Context, userID uuid.UUID) ([]AudioFile, error)
+	// DeleteAudioFile removes the row. When it held the last reference to its
+	// stored object, onLastRef is called with the object key before the change
+	// is committed; an error from onLastRef rolls the deletion back.
+	DeleteAudioFile(ctx context.Context, id uuid.UUID, onLastRef func(s3Key string) error) error
+	// AcquireBlob records a new reference to blob, creating its row on first use.
+	AcquireBlob(ctx context.Context, blob *AudioBlob) error
+	// ListExpiredAudioFiles returns up to limit unpurged files older than their owner's
+	// retention period (users.retention_days, or defaultDays when unset). A period of 0 keeps files forever.
+	ListExpiredAudioFiles(ctx context.Context, defaultDays int, limit int) ([]AudioFile, error)
+	// PurgeAudioFile removes the file's stored object reference and records an AudioPurge.
+	// With keepMetadata the row is kept with its audio reference anonymised instead of deleted.
+	// onLastRef is called as for DeleteAudioFile, after every other change.
+	PurgeAudioFile(ctx context.Context, audioFile *AudioFile, reason string, keepMetadata bool, onLastRef func(s3Key string) error) error
+	// ReleaseBlob drops one reference to the blob, calling onLastRef as for DeleteAudioFile.
+	ReleaseBlob(ctx context.Context, contentSHA256 string, onLastRef func(s3Key string) error) error
+	// DeleteUnreferencedBlob is for an object at s3Key that no audio file uses.
+	// It deletes the object's blob row, whose references were leaked by failed
+	// uploads, and calls onDelete before the change is committed; deleted
+	// reports whether there was a row. It returns ErrBlobInUse and calls nothing
+	// if an audio file uses the content or the blob was acquired after
+	// acquiredBefore, as its upload may still be running.
+	DeleteUnreferencedBlob(ctx context.Context, s3Key string, acquiredBefore time.Time, onDelete func() error) (deleted bool, err error)
 }
+
+// ErrBlobInUse is returned by AudioRepository.DeleteUnreferencedBlob for blobs that must be kept.
+var ErrBlobInUse = errors.New("audio blob in use")
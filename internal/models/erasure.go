@@ -0,0 +1,41 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountDeletion is the erasure job for one account. The row outlives the
+// user as a record that the account was erased.
+type AccountDeletion struct {
+	UserID         uuid.UUID  `db:"user_id"`
+	RequestedAt    time.Time  `db:"requested_at"`
+	StartedAt      *time.Time `db:"started_at"` // Set while a worker holds the job
+	CompletedAt    *time.Time `db:"completed_at"`
+	Attempts       int        `db:"attempts"`
+	LastError      *string    `db:"last_error"`
+	FilesDeleted   int        `db:"files_deleted"`   // By the attempt that completed
+	ObjectsDeleted int        `db:"objects_deleted"` // Stored objects deleted, not shared ones released
+}
+
+// AccountDeletionResponse is returned by DELETE /api/v1/users/me.
+type AccountDeletionResponse struct {
+	Status      string    `json:"status"` // Always "pending": erasure runs in the background
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// ErasureRepository stores account erasure jobs.
+type ErasureRepository interface {
+	// RequestErasure marks the user deleted, so they can no longer log in, and
+	// queues the erasure job. Requesting it again returns the existing job.
+	RequestErasure(ctx context.Context, userID uuid.UUID) (*AccountDeletion, error)
+	// ClaimErasure takes the oldest pending job, or returns (nil, nil) if there is
+	// none. A job held for longer than lease is considered abandoned and can be claimed again.
+	ClaimErasure(ctx context.Context, lease time.Duration) (*AccountDeletion, error)
+	// CompleteErasure deletes the user's remaining rows, including the users row, and marks the job done.
+	CompleteErasure(ctx context.Context, userID uuid.UUID, filesDeleted, objectsDeleted int) error
+	// FailErasure records why a job failed. It is retried once its lease expires.
+	FailErasure(ctx context.Context, userID uuid.UUID, cause string) error
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyRecord is a request made with an Idempotency-Key header and,
+// once it has finished, the response to replay for retries.
+type IdempotencyRecord struct {
+	UserID      uuid.UUID  `db:"user_id"`
+	Key         string     `db:"idempotency_key"`
+	Fingerprint string     `db:"request_fingerprint"`
+	LockedAt    time.Time  `db:"locked_at"`
+	CompletedAt *time.Time `db:"completed_at"` // Nil while the first request is still running
+	StatusCode  *int       `db:"status_code"`
+	ContentType *string    `db:"content_type"`
+	Body        []byte     `db:"response_body"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+}
+
+// IdempotencyRepository stores idempotency keys and their responses.
+type IdempotencyRepository interface {
+	// Reserve claims key for a new request. It returns the new record and true
+	// when the key was free, or the existing record and false when it is in use
+	// or already completed. Expired keys, and keys whose request has been
+	// running longer than lockTimeout, are treated as free.
+	Reserve(ctx context.Context, userID uuid.UUID, key, fingerprint string, ttl, lockTimeout time.Duration) (record *IdempotencyRecord, reserved bool, err error)
+	// Complete stores the response to replay for key. lockedAt is the LockedAt
+	// of the caller's reservation: if the key has since been freed and reserved
+	// by another request, nothing is stored and sql.ErrNoRows is returned.
+	Complete(ctx context.Context, userID uuid.UUID, key string, lockedAt time.Time, statusCode int, contentType string, body []byte) error
+	// Release forgets key, so the request can be retried with it. Like
+	// Complete, it only removes the caller's own reservation.
+	Release(ctx context.Context, userID uuid.UUID, key string, lockedAt time.Time) error
+	// DeleteExpired removes expired keys and returns how many were removed.
+	DeleteExpired(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,9 @@
+package models
+
+import "context"
+
+// TxManager runs a unit of work in a single database transaction.
+// Repository methods called with the ctx passed to fn take part in it.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Usage holds the storage and upload counters tracked for a user.
+type Usage struct {
+	UserID          uuid.UUID `db:"user_id" json:"-"`
+	BytesStored     int64     `db:"bytes_stored" json:"bytes_stored"`
+	FileCount       int       `db:"file_count" json:"file_count"`
+	PeriodStart     time.Time `db:"period_start" json:"period_start"`
+	UploadsInPeriod int       `db:"uploads_in_period" json:"uploads_in_period"`
+	UpdatedAt       time.Time `db:"updated_at" json:"-"`
+}
+
+// QuotaLimits are the limits applied to one role. Zero means unlimited.
+type QuotaLimits struct {
+	MaxBytes            int64 `json:"max_bytes"`
+	MaxFiles            int   `json:"max_files"`
+	MaxUploadsPerPeriod int   `json:"max_uploads_per_period"`
+}
+
+// QuotaKind identifies which limit a request ran into.
+type QuotaKind string
+
+const (
+	QuotaBytes   QuotaKind = "bytes"
+	QuotaFiles   QuotaKind = "files"
+	QuotaUploads QuotaKind = "uploads_per_period"
+)
+
+// QuotaExceededError is returned when reserving an upload would exceed a limit.
+type QuotaExceededError struct {
+	Kind       QuotaKind
+	Limit      int64
+	RetryAfter time.Duration // Set for QuotaUploads: time until the period resets
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded: %s limit %d", e.Kind, e.Limit)
+}
+
+// UsageResponse is returned by GET /api/v1/users/me/usage.
+type UsageResponse struct {
+	Role      string      `json:"role"`
+	Usage     Usage       `json:"usage"`
+	PeriodEnd time.Time   `json:"period_end"`
+	Limits    QuotaLimits `json:"limits"`
+	Remaining QuotaLimits `json:"remaining"` // Zero fields mirror unlimited limits
+}
+
+// UsageRepository defines the interface for per-user usage accounting.
+type UsageRepository interface {
+	// GetUsage returns the counters for a user, zeroed if nothing was recorded yet.
+	GetUsage(ctx context.Context, userID uuid.UUID) (*Usage, error)
+	// ReserveUpload atomically checks limits and counts an upload of sizeBytes,
+	// starting a new period when the current one is older than period.
+	// Returns *QuotaExceededError when a limit would be exceeded.
+	ReserveUpload(ctx context.Context, userID uuid.UUID, sizeBytes int64, limits QuotaLimits, period time.Duration) (*Usage, error)
+	// CancelUpload undoes a reservation for an upload that did not complete.
+	CancelUpload(ctx context.Context, userID uuid.UUID, sizeBytes int64) error
+	// RemoveStored subtracts a deleted file from the stored bytes and file count.
+	RemoveStored(ctx context.Context, userID uuid.UUID, sizeBytes int64) error
+	// ResetPeriod starts a new upload period now, with no uploads counted.
+	// Stored bytes and file count reflect what is stored and are left alone.
+	ResetPeriod(ctx context.Context, userID uuid.UUID) (*Usage, error)
+}
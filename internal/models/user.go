@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
 // User represents a user in the system
 type User struct {
@@ -8,10 +12,38 @@ type User struct {
 	Username  string    `db:"username" json:"username"`
 	Email     string    `db:"email" json:"email"`
 	Password  string    `db:"password_hash" json:"-"` // Never return password hash in JSON
+	Role      string    `db:"role" json:"role"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	// PendingEmail is a requested new email, applied once it has been verified.
+	PendingEmail *string `db:"pending_email" json:"pending_email,omitempty"`
+	// SessionsValidAfter rejects tokens issued before it, e.g. after a password change.
+	SessionsValidAfter *time.Time `db:"sessions_valid_after" json:"-"`
+	// DeletedAt is set when the user asked for their account to be erased.
+	DeletedAt *time.Time `db:"deleted_at" json:"-"`
+	// DisabledAt is set while an admin has disabled the account.
+	DisabledAt     *time.Time `db:"disabled_at" json:"-"`
+	DisabledReason *string    `db:"disabled_reason" json:"-"`
+	// RetentionDays overrides the global audio retention period; nil uses the default, 0 keeps audio forever.
+	RetentionDays *int `db:"retention_days" json:"-"`
 }
 
+// User roles. Quota limits are configured per role.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Errors returned by UserRepository.CreateUser when a unique field is already in use.
+var (
+	ErrEmailTaken    = errors.New("email already registered")
+	ErrUsernameTaken = errors.New("username already taken")
+)
+
+// ErrVerificationInvalid is returned by UserRepository.ConfirmEmailChange for
+// unknown, expired or superseded tokens.
+var ErrVerificationInvalid = errors.New("verification token invalid or expired")
+
 // RegistrationRequest defines the structure for user registration
 type RegistrationRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
@@ -19,6 +51,12 @@ type RegistrationRequest struct {
 	Password string `json:"password" binding:"required,min=8,max=72"`
 }
 
+// RegistrationResponse is returned by POST /api/v1/users/register.
+type RegistrationResponse struct {
+	Message string `json:"message"`
+	UserID  string `json:"user_id"`
+}
+
 // LoginRequest defines the structure for user login
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
@@ -31,10 +69,53 @@ type LoginResponse struct {
 	User  User   `json:"user"` // Optionally return some user details
 }
 
+// UpdateProfileRequest is the body of PATCH /api/v1/users/me. Omitted fields are left unchanged.
+type UpdateProfileRequest struct {
+	Username *string `json:"username" binding:"omitempty,min=3,max=50"`
+	Email    *string `json:"email" binding:"omitempty,email"` // Applied after verification
+}
+
+// VerifyEmailRequest confirms an email change with the token that was sent to the new address.
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ChangePasswordRequest is the body of POST /api/v1/users/me/password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8,max=72"`
+}
+
+// ChangePasswordResponse carries a new token; every token issued before the change is revoked.
+type ChangePasswordResponse struct {
+	Token string `json:"token"`
+}
+
 // UserRepository defines the interface for user data operations.
 // This interface will be implemented by the database package.
 type UserRepository interface {
-	CreateUser(user *User) error
-	GetUserByEmail(email string) (*User, error)
-	GetUserByID(id string) (*User, error) // Added GetUserByID for completeness
+	// CreateUser returns ErrEmailTaken or ErrUsernameTaken if either is already in use.
+	CreateUser(ctx context.Context, user *User) error
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	GetUserByID(ctx context.Context, id string) (*User, error) // Added GetUserByID for completeness
+	// UpdateUsername returns ErrUsernameTaken if the username is already in use.
+	UpdateUsername(ctx context.Context, id, username string) (*User, error)
+	// RequestEmailChange sets the user's pending email and stores the hash of its
+	// verification token, replacing any earlier request.
+	RequestEmailChange(ctx context.Context, id, email, tokenHash string, expiresAt time.Time) (*User, error)
+	// ConfirmEmailChange applies the pending email for tokenHash. It returns
+	// ErrVerificationInvalid or, if the email was taken meanwhile, ErrEmailTaken.
+	ConfirmEmailChange(ctx context.Context, tokenHash string) (*User, error)
+	// UpdatePassword sets the password hash and rejects tokens issued before sessionsValidAfter.
+	UpdatePassword(ctx context.Context, id, passwordHash string, sessionsValidAfter time.Time) error
+	// SearchUsers returns a page of users matching filter, newest first, and the number of matches.
+	SearchUsers(ctx context.Context, filter UserFilter, limit, offset int) ([]User, int, error)
+	// DisableUser blocks the user from logging in and revokes their tokens.
+	// Returns sql.ErrNoRows if the user does not exist.
+	DisableUser(ctx context.Context, id, reason string, sessionsValidAfter time.Time) (*User, error)
+	// EnableUser lifts DisableUser. Returns sql.ErrNoRows if the user does not exist.
+	EnableUser(ctx context.Context, id string) (*User, error)
+	// SetRetentionDays sets the user's audio retention period; nil restores the global default.
+	// Returns sql.ErrNoRows if the user does not exist.
+	SetRetentionDays(ctx context.Context, id string, days *int) (*User, error)
 }
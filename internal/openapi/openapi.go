@@ -0,0 +1,201 @@
+// Package openapi embeds the OpenAPI 3 specification of the /api/v1 routes,
+// serves it with a Swagger UI page, and checks it against the router and the
+// Go response types so the two cannot drift apart.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"example.com/auth_service/internal/apierror"
+	"example.com/auth_service/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// BasePath is the prefix of every path in the specification.
+const BasePath = "/api/v1"
+
+var (
+	//go:embed openapi.json
+	specJSON []byte
+	//go:embed docs.html
+	docsHTML []byte
+	//go:embed docs.js
+	docsJS []byte
+)
+
+// docsContentSecurityPolicy lets the Swagger UI page load its assets from
+// unpkg and fetch the spec from this server, and nothing else.
+const docsContentSecurityPolicy = "default-src 'none'; " +
+	"script-src 'self' https://unpkg.com; " +
+	"style-src 'unsafe-inline' https://unpkg.com; " + // Swagger UI sets inline styles
+	"img-src 'self' data: https://unpkg.com; " +
+	"connect-src 'self'; " +
+	"frame-ancestors 'none'"
+
+// schemaTypes maps component schemas to the Go types they describe.
+var schemaTypes = map[string]any{
+	"RegistrationRequest":     models.RegistrationRequest{},
+	"RegistrationResponse":    models.RegistrationResponse{},
+	"LoginRequest":            models.LoginRequest{},
+	"LoginResponse":           models.LoginResponse{},
+	"User":                    models.User{},
+	"UpdateProfileRequest":    models.UpdateProfileRequest{},
+	"VerifyEmailRequest":      models.VerifyEmailRequest{},
+	"ChangePasswordRequest":   models.ChangePasswordRequest{},
+	"ChangePasswordResponse":  models.ChangePasswordResponse{},
+	"AccountDeletionResponse": models.AccountDeletionResponse{},
+	"Usage":                   models.Usage{},
+	"QuotaLimits":             models.QuotaLimits{},
+	"UsageResponse":           models.UsageResponse{},
+	"UploadAudioResponse":     models.UploadAudioResponse{},
+	"AudioFile":               models.AudioFile{},
+	"AdminUser":               models.AdminUser{},
+	"AdminUserList":           models.AdminUserList{},
+	"AdminUserDetail":         models.AdminUserDetail{},
+	"DisableUserRequest":      models.DisableUserRequest{},
+	"SetRetentionRequest":     models.SetRetentionRequest{},
+	"AdminAudioList":          models.AdminAudioList{},
+	"AuditEntry":              models.AuditEntry{},
+	"AuditLogList":            models.AuditLogList{},
+	"FieldError":              apierror.FieldError{},
+	"Problem":                 apierror.Problem{},
+}
+
+// document is the part of the specification the checks need.
+type document struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+// Spec returns the raw JSON specification.
+func Spec() []byte {
+	return specJSON
+}
+
+// SpecHandler serves the specification.
+func SpecHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", specJSON)
+}
+
+// DocsHandler serves the Swagger UI page. Its script is served from
+// docs.js rather than inlined, so the page works under a strict CSP.
+func DocsHandler(c *gin.Context) {
+	c.Header("Content-Security-Policy", docsContentSecurityPolicy)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsHTML)
+}
+
+// DocsScriptHandler serves the script that starts Swagger UI.
+func DocsScriptHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/javascript; charset=utf-8", docsJS)
+}
+
+// Check reports every difference between the specification and the application:
+// routes under BasePath missing from the spec or documented but not registered,
+// and schemas whose properties differ from the JSON fields of their Go type.
+func Check(routes gin.RoutesInfo) error {
+	var doc document
+	if err := json.Unmarshal(specJSON, &doc); err != nil {
+		return fmt.Errorf("openapi: parse spec: %w", err)
+	}
+	return errors.Join(checkRoutes(&doc, routes), checkSchemas(&doc))
+}
+
+func checkRoutes(doc *document, routes gin.RoutesInfo) error {
+	var errs []error
+	registered := make(map[string]bool)
+	for _, r := range routes {
+		if !strings.HasPrefix(r.Path, BasePath+"/") {
+			continue
+		}
+		path := specPath(strings.TrimPrefix(r.Path, BasePath))
+		method := strings.ToLower(r.Method)
+		registered[method+" "+path] = true
+		if _, ok := doc.Paths[path][method]; !ok {
+			errs = append(errs, fmt.Errorf("openapi: route %s %s is not documented", r.Method, r.Path))
+		}
+	}
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			if !isMethod(method) {
+				continue // e.g. "parameters"
+			}
+			if !registered[method+" "+path] {
+				errs = append(errs, fmt.Errorf("openapi: documented %s %s%s is not registered", strings.ToUpper(method), BasePath, path))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkSchemas(doc *document) error {
+	var errs []error
+	for name, v := range schemaTypes {
+		schema, ok := doc.Components.Schemas[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("openapi: schema %s is missing", name))
+			continue
+		}
+		fields := jsonFields(reflect.TypeOf(v))
+		for _, f := range fields {
+			if _, ok := schema.Properties[f]; !ok {
+				errs = append(errs, fmt.Errorf("openapi: schema %s lacks property %q", name, f))
+			}
+		}
+		for p := range schema.Properties {
+			if !slices.Contains(fields, p) {
+				errs = append(errs, fmt.Errorf("openapi: schema %s property %q has no field in %s", name, p, reflect.TypeOf(v)))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// specPath converts a gin path ("/audio/:id") to OpenAPI form ("/audio/{id}").
+func specPath(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// jsonFields returns the JSON names of the fields encoding/json writes for t.
+func jsonFields(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func isMethod(s string) bool {
+	switch s {
+	case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+		return true
+	}
+	return false
+}
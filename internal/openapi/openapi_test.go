@@ -0,0 +1,46 @@
+package openapi_test
+
+import (
+	"testing"
+
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/internal/openapi"
+	"example.com/auth_service/internal/ratelimit"
+	"example.com/auth_service/internal/routes"
+	"example.com/auth_service/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// router builds the server's routes. Registering them calls no handler, so
+// the handlers and repositories can stay nil.
+func router(t *testing.T) *gin.Engine {
+	t.Helper()
+	appLogger, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatalf("logger: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.Register(r, routes.Deps{
+		Config:  &config.Config{},
+		Limiter: ratelimit.NewMemoryLimiter(),
+		Logger:  appLogger,
+	})
+	return r
+}
+
+// TestSpecMatchesRoutes fails when a route or response type changes without
+// openapi.json; the error lists every difference.
+func TestSpecMatchesRoutes(t *testing.T) {
+	if err := openapi.Check(router(t).Routes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckReportsUndocumentedRoute(t *testing.T) {
+	r := router(t)
+	r.GET(openapi.BasePath+"/undocumented/:id", func(*gin.Context) {})
+	if err := openapi.Check(r.Routes()); err == nil {
+		t.Fatal("Check passed with an undocumented route")
+	}
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"example.com/auth_service/internal/config"
+)
+
+// step is one Allow call, after advancing the clock by advance.
+type step struct {
+	advance time.Duration
+	want    Result
+}
+
+// threePerThreeSeconds refills one token per second.
+var threePerThreeSeconds = config.RateLimit{Burst: 3, Period: 3 * time.Second}
+
+// limitSteps are shared by the memory and Redis tests, which must agree.
+var limitSteps = []struct {
+	name  string
+	limit config.RateLimit
+	steps []step
+}{
+	{
+		name:  "burst then deny",
+		limit: threePerThreeSeconds,
+		steps: []step{
+			{want: Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Second}},
+			{want: Result{Allowed: true, Limit: 3, Remaining: 1, Reset: 2 * time.Second}},
+			{want: Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 3 * time.Second}},
+			{want: Result{Allowed: false, Limit: 3, Remaining: 0, RetryAfter: time.Second, Reset: 3 * time.Second}},
+		},
+	},
+	{
+		name:  "partial refill",
+		limit: threePerThreeSeconds,
+		steps: []step{
+			{want: Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Second}},
+			{want: Result{Allowed: true, Limit: 3, Remaining: 1, Reset: 2 * time.Second}},
+			{want: Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 3 * time.Second}},
+			{advance: 500 * time.Millisecond, want: Result{Allowed: false, Limit: 3, Remaining: 0, RetryAfter: 500 * time.Millisecond, Reset: 2500 * time.Millisecond}},
+			{advance: 500 * time.Millisecond, want: Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 3 * time.Second}},
+		},
+	},
+	{
+		name:  "refill is capped at the burst",
+		limit: threePerThreeSeconds,
+		steps: []step{
+			{want: Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Second}},
+			{advance: time.Hour, want: Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Second}},
+		},
+	},
+	{
+		name:  "burst of one",
+		limit: config.RateLimit{Burst: 1, Period: time.Minute},
+		steps: []step{
+			{want: Result{Allowed: true, Limit: 1, Remaining: 0, Reset: time.Minute}},
+			{advance: 15 * time.Second, want: Result{Allowed: false, Limit: 1, Remaining: 0, RetryAfter: 45 * time.Second, Reset: 45 * time.Second}},
+			{advance: 45 * time.Second, want: Result{Allowed: true, Limit: 1, Remaining: 0, Reset: time.Minute}},
+		},
+	},
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	for _, tt := range limitSteps {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			l := NewMemoryLimiter()
+			l.now = func() time.Time { return now }
+			for i, s := range tt.steps {
+				now = now.Add(s.advance)
+				got, err := l.Allow(context.Background(), "key", tt.limit)
+				if err != nil {
+					t.Fatalf("step %d: Allow: %v", i, err)
+				}
+				if got != s.want {
+					t.Errorf("step %d: Allow = %+v, want %+v", i, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryLimiterKeys(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+	limit := config.RateLimit{Burst: 1, Period: time.Minute}
+
+	if res, _ := l.Allow(context.Background(), "a", limit); !res.Allowed {
+		t.Fatal("first request for a denied")
+	}
+	if res, _ := l.Allow(context.Background(), "a", limit); res.Allowed {
+		t.Fatal("second request for a allowed")
+	}
+	if res, _ := l.Allow(context.Background(), "b", limit); !res.Allowed {
+		t.Fatal("b shares a's bucket")
+	}
+}
+
+func TestMemoryLimiterSweep(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+	l.lastSweep = now
+
+	l.Allow(context.Background(), "short", config.RateLimit{Burst: 1, Period: time.Second})
+	l.Allow(context.Background(), "long", config.RateLimit{Burst: 1, Period: time.Hour})
+
+	now = now.Add(sweepInterval + time.Second)
+	l.Allow(context.Background(), "other", config.RateLimit{Burst: 1, Period: time.Second})
+	if _, ok := l.buckets["short"]; ok {
+		t.Error("full bucket was not swept")
+	}
+	if _, ok := l.buckets["long"]; !ok {
+		t.Error("bucket that is still refilling was swept")
+	}
+}
@@ -0,0 +1,56 @@
+// Package ratelimit implements token-bucket rate limiting with an in-memory
+// backend for a single instance and a Redis backend shared by all instances.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"example.com/auth_service/internal/config"
+)
+
+// Result is the outcome of one Allow call.
+type Result struct {
+	Allowed    bool
+	Limit      int           // Bucket size
+	Remaining  int           // Whole tokens left after this request
+	RetryAfter time.Duration // Until the next token, when not allowed
+	Reset      time.Duration // Until the bucket is full again
+}
+
+// Limiter takes one token from the bucket identified by key.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit config.RateLimit) (Result, error)
+}
+
+// bucket is the state of one token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills b for the time elapsed since its last use and takes one token
+// if there is one. Both backends share this arithmetic.
+func (b *bucket) take(now time.Time, limit config.RateLimit) Result {
+	rate := float64(limit.Burst) / limit.Period.Seconds() // Tokens per second
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*rate)
+	}
+	b.last = now
+
+	res := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((float64(limit.Burst) - b.tokens) / rate)
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"example.com/auth_service/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript is the bucket arithmetic of bucket.take, run atomically
+// in Redis on the server's clock so instances with skewed clocks agree.
+// The hash expires once the bucket would be full again.
+//
+// KEYS[1] bucket key; ARGV[1] burst; ARGV[2] period in ms.
+// Returns {allowed, tokens * 1000, now in ms}.
+var tokenBucketScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+local rate = burst / period
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+if now > last then
+  tokens = math.min(burst, tokens + (now - last) * rate)
+end
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate) + 1000)
+return {allowed, math.floor(tokens * 1000), now}
+`)
+
+// RedisLimiter keeps buckets in Redis, so limits hold across all instances.
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisLimiter connects to the Redis server at url (redis://host:port/db).
+func NewRedisLimiter(ctx context.Context, url string) (*RedisLimiter, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	return &RedisLimiter{client: client}, nil
+}
+
+// Allow implements Limiter.
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit config.RateLimit) (Result, error) {
+	vals, err := tokenBucketScript.Run(ctx, l.client, []string{key}, limit.Burst, limit.Period.Milliseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit script: %w", err)
+	}
+	// Redis already took the token; replay its arithmetic to fill in the Result.
+	b := bucket{tokens: float64(vals[1])/1000 + float64(vals[0]), last: time.UnixMilli(vals[2])}
+	return b.take(b.last, limit), nil
+}
+
+// Ping checks the connection, for readiness checks.
+func (l *RedisLimiter) Ping(ctx context.Context) error {
+	return l.client.Ping(ctx).Err()
+}
+
+// Close closes the connection pool.
+func (l *RedisLimiter) Close() error {
+	return l.client.Close()
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func redisLimiter(t *testing.T) (*RedisLimiter, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	l, err := NewRedisLimiter(context.Background(), "redis://"+mr.Addr())
+	if err != nil {
+		t.Fatalf("NewRedisLimiter: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l, mr
+}
+
+func TestRedisLimiter(t *testing.T) {
+	for _, tt := range limitSteps {
+		t.Run(tt.name, func(t *testing.T) {
+			l, mr := redisLimiter(t)
+			// The script reads the clock with TIME, which miniredis takes from SetTime.
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, s := range tt.steps {
+				now = now.Add(s.advance)
+				mr.SetTime(now)
+				got, err := l.Allow(context.Background(), "key", tt.limit)
+				if err != nil {
+					t.Fatalf("step %d: Allow: %v", i, err)
+				}
+				if got != s.want {
+					t.Errorf("step %d: Allow = %+v, want %+v", i, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRedisLimiterExpiry(t *testing.T) {
+	l, mr := redisLimiter(t)
+	mr.SetTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if _, err := l.Allow(context.Background(), "key", threePerThreeSeconds); err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+	// The bucket refills in 1s; the key outlives that by a second.
+	if ttl := mr.TTL("key"); ttl != 2*time.Second {
+		t.Errorf("TTL = %v, want 2s", ttl)
+	}
+	mr.FastForward(3 * time.Second)
+	if mr.Exists("key") {
+		t.Error("key still exists after it expired")
+	}
+}
+
+func TestRedisLimiterUnavailable(t *testing.T) {
+	l, mr := redisLimiter(t)
+	if err := l.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	mr.Close()
+	if err := l.Ping(context.Background()); err == nil {
+		t.Error("Ping succeeded with Redis down")
+	}
+	if _, err := l.Allow(context.Background(), "key", threePerThreeSeconds); err == nil {
+		t.Error("Allow succeeded with Redis down")
+	}
+}
+
+func TestNewRedisLimiterErrors(t *testing.T) {
+	if _, err := NewRedisLimiter(context.Background(), "http://localhost"); err == nil {
+		t.Error("invalid URL accepted")
+	}
+	mr := miniredis.RunT(t)
+	addr := mr.Addr()
+	mr.Close()
+	if _, err := NewRedisLimiter(context.Background(), "redis://"+addr); err == nil {
+		t.Error("unreachable server accepted")
+	}
+}
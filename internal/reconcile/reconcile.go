@@ -0,0 +1,266 @@
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/internal/storage"
+	"example.com/auth_service/pkg/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	pageSize        = 1000 // Rows fetched from audio_files per query
+	maxReportedKeys = 1000 // Cap on keys kept in a Report to bound memory on large buckets
+)
+
+// Options controls a single reconciliation pass.
+type Options struct {
+	// DryRun reports orphans without deleting anything.
+	DryRun bool
+	// MinAge skips objects and rows younger than this, so uploads that are
+	// still between the S3 write and the metadata insert are not treated as orphans.
+	MinAge time.Duration
+}
+
+// Report summarises the result of a reconciliation pass.
+type Report struct {
+	DryRun         bool      `json:"dry_run"`
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+	ObjectsScanned int       `json:"objects_scanned"`
+	RowsScanned    int       `json:"rows_scanned"`
+	OrphanObjects  []string  `json:"orphan_objects"` // S3 keys with no audio_files row
+	OrphanRows     []string  `json:"orphan_rows"`    // s3_key values of rows with no S3 object
+	OrphanObjectsN int       `json:"orphan_objects_total"`
+	OrphanRowsN    int       `json:"orphan_rows_total"`
+	ObjectsDeleted int       `json:"objects_deleted"`
+	RowsDeleted    int       `json:"rows_deleted"`
+	BlobsDeleted   int       `json:"blobs_deleted"` // audio_blobs rows of orphan objects, with references leaked by failed uploads
+}
+
+// Reconciler finds and optionally removes stored objects without metadata rows
+// and metadata rows without stored objects.
+type Reconciler struct {
+	store     storage.BlobStore
+	audioRepo models.AudioRepository
+	usageRepo models.UsageRepository
+	txManager models.TxManager
+	logger    *logger.Logger
+}
+
+// NewReconciler creates a new Reconciler.
+func NewReconciler(store storage.BlobStore, audioRepo models.AudioRepository, usageRepo models.UsageRepository, txManager models.TxManager, appLogger *logger.Logger) *Reconciler {
+	return &Reconciler{
+		store:     store,
+		audioRepo: audioRepo,
+		usageRepo: usageRepo,
+		txManager: txManager,
+		logger:    appLogger,
+	}
+}
+
+// Run performs one reconciliation pass.
+// Both sides are walked in byte-wise key order and merged, so neither the
+// bucket listing nor the table is ever held in memory in full.
+func (r *Reconciler) Run(ctx context.Context, opts Options) (*Report, error) {
+	report := &Report{DryRun: opts.DryRun, StartedAt: time.Now()}
+	cutoff := report.StartedAt.Add(-opts.MinAge)
+	rows := &rowIterator{ctx: ctx, repo: r.audioRepo}
+
+	err := r.store.List(ctx, "", func(obj storage.ObjectInfo) error {
+		report.ObjectsScanned++
+
+		// Every row sorting before this object has no matching object.
+		for {
+			row, err := rows.peek()
+			if err != nil {
+				return err
+			}
+			if row == nil || row.S3Key >= obj.Key {
+				break
+			}
+			if err := r.handleOrphanRow(ctx, row, cutoff, opts, report); err != nil {
+				return err
+			}
+			rows.next()
+		}
+
+		row, err := rows.peek()
+		if err != nil {
+			return err
+		}
+		if row == nil || row.S3Key != obj.Key {
+			return r.handleOrphanObject(ctx, obj, cutoff, opts, report)
+		}
+
+		// Deduplicated content is referenced by several rows; they all match.
+		for row != nil && row.S3Key == obj.Key {
+			report.RowsScanned++
+			rows.next()
+			if row, err = rows.peek(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("reconcile: %w", err)
+	}
+
+	// Whatever is left in the table sorts after the last object.
+	for {
+		row, err := rows.peek()
+		if err != nil {
+			return report, fmt.Errorf("reconcile: %w", err)
+		}
+		if row == nil {
+			break
+		}
+		if err := r.handleOrphanRow(ctx, row, cutoff, opts, report); err != nil {
+			return report, fmt.Errorf("reconcile: %w", err)
+		}
+		rows.next()
+	}
+
+	report.FinishedAt = time.Now()
+	r.logger.Info("Reconciliation finished",
+		zap.Bool("dry_run", report.DryRun),
+		zap.Int("objects_scanned", report.ObjectsScanned),
+		zap.Int("rows_scanned", report.RowsScanned),
+		zap.Int("orphan_objects", report.OrphanObjectsN),
+		zap.Int("orphan_rows", report.OrphanRowsN),
+		zap.Int("objects_deleted", report.ObjectsDeleted),
+		zap.Int("rows_deleted", report.RowsDeleted),
+		zap.Int("blobs_deleted", report.BlobsDeleted),
+		zap.Duration("duration", report.FinishedAt.Sub(report.StartedAt)))
+	return report, nil
+}
+
+// RunPeriodically runs a reconciliation pass every interval until ctx is cancelled.
+// Errors are logged and do not stop the loop.
+func (r *Reconciler) RunPeriodically(ctx context.Context, interval time.Duration, opts Options) {
+	r.logger.Info("Periodic reconciliation started", zap.Duration("interval", interval), zap.Bool("dry_run", opts.DryRun))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Periodic reconciliation stopped")
+			return
+		case <-ticker.C:
+			if _, err := r.Run(ctx, opts); err != nil {
+				r.logger.Error("Periodic reconciliation failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (r *Reconciler) handleOrphanObject(ctx context.Context, obj storage.ObjectInfo, cutoff time.Time, opts Options, report *Report) error {
+	if obj.LastModified.After(cutoff) {
+		return nil
+	}
+	report.OrphanObjectsN++
+	if len(report.OrphanObjects) < maxReportedKeys {
+		report.OrphanObjects = append(report.OrphanObjects, obj.Key)
+	}
+	r.logger.Warn("Orphan S3 object without metadata row", zap.String("s3_key", obj.Key), zap.Int64("size_bytes", obj.SizeBytes))
+	if opts.DryRun {
+		return nil
+	}
+	// An upload that failed after taking its blob reference leaves the blob row
+	// behind with a count that never reaches zero; it goes with the object.
+	blobDeleted, err := r.audioRepo.DeleteUnreferencedBlob(ctx, obj.Key, cutoff, func() error {
+		return r.store.Delete(ctx, obj.Key)
+	})
+	if errors.Is(err, models.ErrBlobInUse) {
+		r.logger.Info("Keeping orphan S3 object whose blob is in use", zap.String("s3_key", obj.Key))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	report.ObjectsDeleted++
+	if blobDeleted {
+		report.BlobsDeleted++
+	}
+	return nil
+}
+
+func (r *Reconciler) handleOrphanRow(ctx context.Context, row *models.AudioFile, cutoff time.Time, opts Options, report *Report) error {
+	report.RowsScanned++
+	if row.UploadedAt.After(cutoff) {
+		return nil
+	}
+	report.OrphanRowsN++
+	if len(report.OrphanRows) < maxReportedKeys {
+		report.OrphanRows = append(report.OrphanRows, row.S3Key)
+	}
+	r.logger.Warn("Orphan metadata row without S3 object", zap.String("id", row.ID.String()), zap.String("s3_key", row.S3Key))
+	if opts.DryRun {
+		return nil
+	}
+	err := r.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// The object is already gone, so deleting it again on the last reference is harmless.
+		err := r.audioRepo.DeleteAudioFile(ctx, row.ID, func(s3Key string) error {
+			return r.store.Delete(ctx, s3Key)
+		})
+		if err != nil {
+			return err
+		}
+		return r.usageRepo.RemoveStored(ctx, row.UserID, row.SizeBytes)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil // Deleted concurrently
+	}
+	if err != nil {
+		return err
+	}
+	report.RowsDeleted++
+	return nil
+}
+
+// rowIterator pages through audio_files in (s3_key, id) order.
+type rowIterator struct {
+	ctx     context.Context
+	repo    models.AudioRepository
+	buf     []models.AudioFile
+	pos     int
+	lastKey string    // Key and ID of the last row fetched, where the next page starts
+	lastID  uuid.UUID // uuid.Nil sorts first, so the first page starts at ("", Nil)
+	done    bool
+}
+
+// peek returns the current row without consuming it, or nil when exhausted.
+func (it *rowIterator) peek() (*models.AudioFile, error) {
+	if it.pos < len(it.buf) {
+		return &it.buf[it.pos], nil
+	}
+	if it.done {
+		return nil, nil
+	}
+	page, err := it.repo.ListAudioFilesAfterKey(it.ctx, it.lastKey, it.lastID, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	it.buf, it.pos = page, 0
+	if len(page) < pageSize {
+		it.done = true
+	}
+	if len(page) == 0 {
+		return nil, nil
+	}
+	last := page[len(page)-1]
+	it.lastKey, it.lastID = last.S3Key, last.ID
+	return &it.buf[0], nil
+}
+
+// next consumes the current row.
+func (it *rowIterator) next() {
+	it.pos++
+}
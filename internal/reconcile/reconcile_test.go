@@ -0,0 +1,210 @@
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/internal/storage"
+	"example.com/auth_service/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// fakeAudioRepo keeps audio_files rows in memory, with the ordering and
+// deletion semantics of the database implementation.
+type fakeAudioRepo struct {
+	models.AudioRepository // Methods the reconciler does not use panic
+	files                  []models.AudioFile
+	blobs                  map[string]*fakeBlob // By s3_key
+}
+
+type fakeBlob struct {
+	sha256     string
+	refCount   int
+	acquiredAt time.Time
+}
+
+func (r *fakeAudioRepo) ListAudioFilesAfterKey(_ context.Context, afterKey string, afterID uuid.UUID, limit int) ([]models.AudioFile, error) {
+	compare := func(a, b models.AudioFile) int {
+		if c := strings.Compare(a.S3Key, b.S3Key); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	}
+	sorted := slices.Clone(r.files)
+	slices.SortFunc(sorted, compare)
+	after := models.AudioFile{S3Key: afterKey, ID: afterID}
+	var page []models.AudioFile
+	for _, f := range sorted {
+		if compare(f, after) > 0 && len(page) < limit {
+			page = append(page, f)
+		}
+	}
+	return page, nil
+}
+
+func (r *fakeAudioRepo) DeleteAudioFile(_ context.Context, id uuid.UUID, onLastRef func(s3Key string) error) error {
+	i := slices.IndexFunc(r.files, func(f models.AudioFile) bool { return f.ID == id })
+	if i < 0 {
+		return nil
+	}
+	key := r.files[i].S3Key
+	r.files = slices.Delete(r.files, i, i+1)
+	if !slices.ContainsFunc(r.files, func(f models.AudioFile) bool { return f.S3Key == key }) {
+		return onLastRef(key)
+	}
+	return nil
+}
+
+func (r *fakeAudioRepo) DeleteUnreferencedBlob(_ context.Context, s3Key string, acquiredBefore time.Time, onDelete func() error) (bool, error) {
+	blob, ok := r.blobs[s3Key]
+	if !ok {
+		return false, onDelete()
+	}
+	inUse := slices.ContainsFunc(r.files, func(f models.AudioFile) bool { return f.ContentSHA256 == blob.sha256 })
+	if inUse || blob.acquiredAt.After(acquiredBefore) {
+		return false, models.ErrBlobInUse
+	}
+	if err := onDelete(); err != nil {
+		return false, err
+	}
+	delete(r.blobs, s3Key)
+	return true, nil
+}
+
+func (r *fakeAudioRepo) keys() []string {
+	var keys []string
+	for _, f := range r.files {
+		keys = append(keys, f.S3Key)
+	}
+	return keys
+}
+
+type fakeUsageRepo struct {
+	models.UsageRepository
+	removed int64
+}
+
+func (r *fakeUsageRepo) RemoveStored(_ context.Context, _ uuid.UUID, sizeBytes int64) error {
+	r.removed += sizeBytes
+	return nil
+}
+
+// fakeTx runs fn directly; the fakes have nothing to roll back.
+type fakeTx struct{}
+
+func (fakeTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func newReconciler(t *testing.T, store storage.BlobStore, audioRepo *fakeAudioRepo, usageRepo *fakeUsageRepo) *Reconciler {
+	t.Helper()
+	appLogger, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatalf("logger: %v", err)
+	}
+	return NewReconciler(store, audioRepo, usageRepo, fakeTx{}, appLogger)
+}
+
+func addFile(repo *fakeAudioRepo, key string) {
+	repo.files = append(repo.files, models.AudioFile{
+		ID:         uuid.New(),
+		UserID:     uuid.New(),
+		S3Key:      key,
+		SizeBytes:  10,
+		UploadedAt: time.Now().Add(-time.Hour),
+	})
+}
+
+func putObject(t *testing.T, store storage.BlobStore, key string) {
+	t.Helper()
+	if _, err := store.Put(context.Background(), key, strings.NewReader("audio"), "audio/wav"); err != nil {
+		t.Fatalf("Put(%q): %v", key, err)
+	}
+}
+
+// TestRunPagesWithinSharedKey puts a page boundary inside a group of
+// deduplicated rows sharing a key, which must all be visited.
+func TestRunPagesWithinSharedKey(t *testing.T) {
+	store := storage.NewMemoryStore()
+	audioRepo := &fakeAudioRepo{}
+	usageRepo := &fakeUsageRepo{}
+
+	putObject(t, store, "blobs/a")
+	for i := 0; i < pageSize-1; i++ {
+		addFile(audioRepo, "blobs/a")
+	}
+	const orphans = 5 // The first page ends after one of them
+	for i := 0; i < orphans; i++ {
+		addFile(audioRepo, "blobs/m")
+	}
+
+	report, err := newReconciler(t, store, audioRepo, usageRepo).Run(context.Background(), Options{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.RowsScanned != pageSize-1+orphans {
+		t.Errorf("RowsScanned = %d, want %d", report.RowsScanned, pageSize-1+orphans)
+	}
+	if report.OrphanRowsN != orphans || report.RowsDeleted != orphans {
+		t.Errorf("orphan rows found %d, deleted %d; want %d", report.OrphanRowsN, report.RowsDeleted, orphans)
+	}
+	if slices.Contains(audioRepo.keys(), "blobs/m") {
+		t.Error("orphan rows left behind")
+	}
+	if usageRepo.removed != orphans*10 {
+		t.Errorf("usage removed %d bytes, want %d", usageRepo.removed, orphans*10)
+	}
+}
+
+// TestRunDeletesLeakedBlobs covers uploads that failed after AcquireBlob and
+// Put but before SaveAudioFile: the object has no row, and its blob row has a
+// reference nobody will release.
+func TestRunDeletesLeakedBlobs(t *testing.T) {
+	tests := []struct {
+		name            string
+		acquiredAt      time.Duration // Relative to now; later than now is after the pass started
+		dryRun          bool
+		wantObjectKept  bool
+		wantBlobDeleted bool
+	}{
+		{name: "leaked reference", acquiredAt: -time.Hour, wantBlobDeleted: true},
+		{name: "upload still running", acquiredAt: time.Hour, wantObjectKept: true},
+		{name: "dry run", acquiredAt: -time.Hour, dryRun: true, wantObjectKept: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewMemoryStore()
+			audioRepo := &fakeAudioRepo{blobs: map[string]*fakeBlob{
+				"blobs/leaked": {sha256: "leaked", refCount: 1, acquiredAt: time.Now().Add(tt.acquiredAt)},
+			}}
+			putObject(t, store, "blobs/leaked")
+			putObject(t, store, "blobs/no-row") // An orphan without a blob row is deleted as before
+
+			report, err := newReconciler(t, store, audioRepo, &fakeUsageRepo{}).Run(context.Background(), Options{DryRun: tt.dryRun})
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+
+			_, err = store.Head(context.Background(), "blobs/leaked")
+			if objectKept := err == nil; objectKept != tt.wantObjectKept {
+				t.Errorf("object kept = %v, want %v", objectKept, tt.wantObjectKept)
+			}
+			_, blobKept := audioRepo.blobs["blobs/leaked"]
+			if blobKept == tt.wantBlobDeleted {
+				t.Errorf("blob row kept = %v, want %v", blobKept, !tt.wantBlobDeleted)
+			}
+			if tt.wantBlobDeleted && report.BlobsDeleted != 1 {
+				t.Errorf("BlobsDeleted = %d, want 1", report.BlobsDeleted)
+			}
+			_, err = store.Head(context.Background(), "blobs/no-row")
+			if noRowKept := err == nil; noRowKept != tt.dryRun {
+				t.Errorf("orphan without blob row kept = %v, want %v", noRowKept, tt.dryRun)
+			}
+		})
+	}
+}
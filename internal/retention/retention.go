@@ -0,0 +1,130 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/internal/storage"
+	"example.com/auth_service/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// PurgeReason is recorded in audio_purges for files removed by the sweeper.
+const PurgeReason = "retention"
+
+// purgeTimeout bounds the purge of one file. It runs on after cancellation,
+// so without a bound a hung storage delete would block shutdown.
+const purgeTimeout = time.Minute
+
+// Sweeper deletes stored audio that has outlived its retention period.
+type Sweeper struct {
+	store     storage.BlobStore
+	audioRepo models.AudioRepository
+	usageRepo models.UsageRepository
+	txManager models.TxManager
+	cfg       config.RetentionConfig
+	logger    *logger.Logger
+}
+
+// NewSweeper creates a new Sweeper.
+func NewSweeper(store storage.BlobStore, audioRepo models.AudioRepository, usageRepo models.UsageRepository, txManager models.TxManager, cfg config.RetentionConfig, appLogger *logger.Logger) *Sweeper {
+	return &Sweeper{
+		store:     store,
+		audioRepo: audioRepo,
+		usageRepo: usageRepo,
+		txManager: txManager,
+		cfg:       cfg,
+		logger:    appLogger,
+	}
+}
+
+// Sweep purges every expired file, one batch at a time, and returns how many were purged.
+// A file that fails to purge is logged and left for the next sweep.
+func (s *Sweeper) Sweep(ctx context.Context) (int, error) {
+	purged := 0
+	for {
+		files, err := s.audioRepo.ListExpiredAudioFiles(ctx, s.cfg.DefaultDays, s.cfg.BatchSize)
+		if err != nil {
+			return purged, fmt.Errorf("retention sweep: %w", err)
+		}
+
+		failed := 0
+		for i := range files {
+			if err := ctx.Err(); err != nil {
+				return purged, err // Shutting down; the rest waits for the next run
+			}
+			if err := s.purge(ctx, &files[i]); err != nil {
+				failed++
+				s.logger.Error("Failed to purge expired audio file", zap.String("id", files[i].ID.String()), zap.Error(err))
+				continue
+			}
+			purged++
+		}
+
+		// Stop on a short batch, or when a whole batch failed and would be listed again.
+		if len(files) < s.cfg.BatchSize || failed == len(files) {
+			break
+		}
+	}
+	if purged > 0 {
+		s.logger.Info("Retention sweep finished", zap.Int("purged", purged), zap.Bool("metadata_kept", s.cfg.KeepMetadata))
+	}
+	return purged, nil
+}
+
+// RunPeriodically sweeps every cfg.SweepInterval until ctx is cancelled.
+func (s *Sweeper) RunPeriodically(ctx context.Context) {
+	s.logger.Info("Retention sweeper started",
+		zap.Duration("interval", s.cfg.SweepInterval),
+		zap.Int("default_days", s.cfg.DefaultDays),
+		zap.Bool("keep_metadata", s.cfg.KeepMetadata))
+	ticker := time.NewTicker(s.cfg.SweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Retention sweeper stopped")
+			return
+		case <-ticker.C:
+			if _, err := s.Sweep(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("Retention sweep failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// purge removes one file and its usage in a single transaction.
+// It is not interrupted by cancellation, so shutdown waits for the file in
+// progress, for up to purgeTimeout.
+func (s *Sweeper) purge(ctx context.Context, file *models.AudioFile) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), purgeTimeout)
+	defer cancel()
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var lastRefKey string
+		err := s.audioRepo.PurgeAudioFile(ctx, file, PurgeReason, s.cfg.KeepMetadata, func(s3Key string) error {
+			lastRefKey = s3Key
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.usageRepo.RemoveStored(ctx, file.UserID, file.SizeBytes); err != nil {
+			return err
+		}
+		// The object goes last, as it cannot be rolled back: if anything
+		// before fails, the rows come back with their object still in place.
+		if lastRefKey == "" {
+			return nil
+		}
+		return s.store.Delete(ctx, lastRefKey)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil // Already gone
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/internal/storage"
+	"example.com/auth_service/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// fakeAudioRepo purges by calling onLastRef with the file's key, as the
+// database does for the last reference.
+type fakeAudioRepo struct {
+	models.AudioRepository
+	purged []uuid.UUID
+}
+
+func (r *fakeAudioRepo) PurgeAudioFile(_ context.Context, file *models.AudioFile, _ string, _ bool, onLastRef func(s3Key string) error) error {
+	r.purged = append(r.purged, file.ID)
+	return onLastRef(file.S3Key)
+}
+
+type fakeUsageRepo struct {
+	models.UsageRepository
+	err error
+}
+
+func (r *fakeUsageRepo) RemoveStored(context.Context, uuid.UUID, int64) error {
+	return r.err
+}
+
+type fakeTx struct{}
+
+func (fakeTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func TestPurgeDeletesObjectLast(t *testing.T) {
+	tests := []struct {
+		name           string
+		usageErr       error
+		wantObjectKept bool
+	}{
+		{name: "success"},
+		{name: "usage update fails", usageErr: errors.New("db down"), wantObjectKept: true},
+	}
+	appLogger, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatalf("logger: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewMemoryStore()
+			if _, err := store.Put(context.Background(), "blobs/a", strings.NewReader("audio"), "audio/wav"); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			file := &models.AudioFile{ID: uuid.New(), UserID: uuid.New(), S3Key: "blobs/a", SizeBytes: 5, UploadedAt: time.Now()}
+			s := NewSweeper(store, &fakeAudioRepo{}, &fakeUsageRepo{err: tt.usageErr}, fakeTx{}, config.RetentionConfig{}, appLogger)
+
+			err := s.purge(context.Background(), file)
+			if (err != nil) != (tt.usageErr != nil) {
+				t.Errorf("purge = %v, want error %v", err, tt.usageErr)
+			}
+			_, err = store.Head(context.Background(), "blobs/a")
+			if kept := err == nil; kept != tt.wantObjectKept {
+				t.Errorf("object kept = %v, want %v", kept, tt.wantObjectKept)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Package routes registers the HTTP routes of the service. It is separate from
+// cmd/server so tests can build the same router, e.g. to check it against the
+// OpenAPI specification.
+package routes
+
+import (
+	"net/http"
+
+	"example.com/auth_service/internal/apierror"
+	"example.com/auth_service/internal/auth"
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/internal/handlers"
+	"example.com/auth_service/internal/metrics"
+	"example.com/auth_service/internal/middleware"
+	"example.com/auth_service/internal/models"
+	"example.com/auth_service/internal/openapi"
+	"example.com/auth_service/internal/ratelimit"
+	"example.com/auth_service/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// Deps are the handlers and services the routes use. Nothing is called while
+// registering, so tests that only inspect the routes can leave them nil.
+type Deps struct {
+	Config          *config.Config
+	AuthService     *auth.AuthService
+	Limiter         ratelimit.Limiter
+	IdempotencyRepo models.IdempotencyRepository
+	AuditRepo       models.AuditRepository
+	Logger          *logger.Logger
+
+	Health  *handlers.HealthHandler
+	User    *handlers.UserHandler
+	Account *handlers.AccountHandler
+	Audio   *handlers.AudioHandler
+	Admin   *handlers.AdminHandler
+}
+
+// Register adds every route to router. Global middleware (request IDs,
+// logging, error rendering, CORS, ...) is the caller's to install first.
+func Register(router *gin.Engine, d Deps) {
+	cfg := d.Config
+
+	// Rate limiting: per client IP before login, per user after
+	rateLimit := func(policy string, limit config.RateLimit, key middleware.RateLimitKeyFunc) gin.HandlerFunc {
+		return middleware.RateLimit(d.Limiter, policy, limit, key, d.Logger)
+	}
+	userRateLimit := rateLimit("default", cfg.RateLimit.Default, middleware.ByUser)
+
+	// Retried uploads with the same Idempotency-Key replay the first response.
+	// It runs before the upload limit, so replays do not use up the bucket.
+	idempotent := middleware.Idempotency(d.IdempotencyRepo, cfg.Idempotency, handlers.MaxUploadSize, d.Logger)
+
+	apiV1 := router.Group(openapi.BasePath)
+	{
+		authMW := middleware.AuthMiddleware(d.AuthService, d.Logger)
+
+		userRoutes := apiV1.Group("/users")
+		{
+			userRoutes.POST("/register", rateLimit("register", cfg.RateLimit.Register, middleware.ByClientIP), apierror.Handler(d.User.RegisterUser))
+			userRoutes.POST("/login", rateLimit("login", cfg.RateLimit.Login, middleware.ByClientIP), apierror.Handler(d.User.LoginUser))
+			userRoutes.POST("/verify-email", rateLimit("verify_email", cfg.RateLimit.Login, middleware.ByClientIP), apierror.Handler(d.User.VerifyEmail))
+
+			// Current user routes (protected)
+			meRoutes := userRoutes.Group("/me")
+			meRoutes.Use(authMW, userRateLimit)
+			{
+				meRoutes.GET("", apierror.Handler(d.User.GetProfile))
+				meRoutes.PATCH("", apierror.Handler(d.User.UpdateProfile))
+				meRoutes.DELETE("", apierror.Handler(d.Account.DeleteAccount))
+				meRoutes.GET("/export", rateLimit("export", cfg.RateLimit.Export, middleware.ByUser), apierror.Handler(d.Account.ExportData))
+				// Checks the current password, so it is limited like login
+				meRoutes.POST("/password", rateLimit("password", cfg.RateLimit.Login, middleware.ByUser), apierror.Handler(d.User.ChangePassword))
+				meRoutes.GET("/usage", apierror.Handler(d.User.GetUsage))
+			}
+		}
+
+		// Audio routes (protected)
+		audioRoutes := apiV1.Group("/audio")
+		audioRoutes.Use(authMW, userRateLimit) // Apply auth middleware to all /audio routes
+		{
+			audioRoutes.POST("/upload", idempotent, rateLimit("upload", cfg.RateLimit.Upload, middleware.ByUser), apierror.Handler(d.Audio.UploadAudioFile))
+		}
+
+		// Admin routes: every request is written to the audit log as the named action
+		audited := func(action string) gin.HandlerFunc {
+			return middleware.Audit(d.AuditRepo, action, d.Logger)
+		}
+		adminRoutes := apiV1.Group("/admin")
+		adminRoutes.Use(authMW, middleware.RequireRole(models.RoleAdmin, d.Logger), userRateLimit)
+		{
+			adminRoutes.GET("/users", audited(models.AuditUserSearch), apierror.Handler(d.Admin.ListUsers))
+			adminRoutes.GET("/users/:id", audited(models.AuditUserGet), apierror.Handler(d.Admin.GetUser))
+			adminRoutes.POST("/users/:id/disable", audited(models.AuditUserDisable), apierror.Handler(d.Admin.DisableUser))
+			adminRoutes.POST("/users/:id/enable", audited(models.AuditUserEnable), apierror.Handler(d.Admin.EnableUser))
+			adminRoutes.POST("/users/:id/quota/reset", audited(models.AuditQuotaReset), apierror.Handler(d.Admin.ResetQuota))
+			adminRoutes.PUT("/users/:id/retention", audited(models.AuditUserRetention), apierror.Handler(d.Admin.SetRetention))
+			adminRoutes.GET("/users/:id/audio", audited(models.AuditAudioList), apierror.Handler(d.Admin.ListUserAudio))
+			adminRoutes.GET("/audio/:id", audited(models.AuditAudioGet), apierror.Handler(d.Admin.GetAudio))
+			adminRoutes.GET("/audio/:id/content", audited(models.AuditAudioDownload), apierror.Handler(d.Admin.DownloadAudio))
+			adminRoutes.GET("/audit", audited(models.AuditLogRead), apierror.Handler(d.Admin.ListAudit))
+		}
+
+		apiV1.GET("/openapi.json", openapi.SpecHandler)
+	}
+
+	router.NoRoute(func(c *gin.Context) {
+		apierror.Abort(c, apierror.NotFound("No route matches "+c.Request.URL.Path+"."))
+	})
+	router.NoMethod(func(c *gin.Context) {
+		apierror.Abort(c, apierror.New(http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed,
+			"Method "+c.Request.Method+" is not allowed for "+c.Request.URL.Path+"."))
+	})
+
+	router.GET("/docs", openapi.DocsHandler)
+	router.GET("/docs/docs.js", openapi.DocsScriptHandler)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	router.GET("/healthz", d.Health.Liveness)
+	router.GET("/readyz", d.Health.Readiness)
+	router.GET("/ping", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "pong"})
+	})
+}
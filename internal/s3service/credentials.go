@@ -0,0 +1,38 @@
+package s3service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// rotatingCredentials is a static credentials provider whose keys can be
+// replaced at runtime, e.g. when a mounted secret is rotated.
+type rotatingCredentials struct {
+	creds atomic.Pointer[aws.Credentials]
+}
+
+func newRotatingCredentials(accessKeyID, secretAccessKey string) *rotatingCredentials {
+	p := &rotatingCredentials{}
+	p.set(accessKeyID, secretAccessKey)
+	return p
+}
+
+func (p *rotatingCredentials) set(accessKeyID, secretAccessKey string) {
+	p.creds.Store(&aws.Credentials{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		Source:          "RotatingCredentials",
+	})
+}
+
+// Retrieve implements aws.CredentialsProvider.
+func (p *rotatingCredentials) Retrieve(context.Context) (aws.Credentials, error) {
+	creds := *p.creds.Load()
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return aws.Credentials{}, errors.New("s3service: access key ID and secret access key are required")
+	}
+	return creds, nil
+}
@@ -2,27 +2,35 @@ package s3service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"example.com/auth_service/internal/config"
+	"example.com/auth_service/internal/storage"
 	"example.com/auth_service/pkg/logger"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"go.uber.org/zap"
 )
 
 // S3Service provides methods to interact with S3-compatible storage.
+// It is the storage.BlobStore used in production.
 type S3Service struct {
 	client     *s3.Client
+	creds      *rotatingCredentials
+	credsCache *aws.CredentialsCache // Invalidated when creds rotate
 	bucketName string
 	logger     *logger.Logger
 	endpoint   string
 }
 
+var _ storage.BlobStore = (*S3Service)(nil)
+
 // NewS3Service creates a new S3Service.
 func NewS3Service(cfg config.S3Config, appLogger *logger.Logger) (*S3Service, error) {
 	var awsSDKConfig aws.Config
@@ -44,9 +52,12 @@ func NewS3Service(cfg config.S3Config, appLogger *logger.Logger) (*S3Service, er
 	// Load AWS configuration
 	// For MinIO, region might not be strictly necessary but SDK expects it.
 	// Static credentials are used here as per common practice for MinIO or specific IAM user for S3.
+	// They can be swapped at runtime with SetCredentials.
+	creds := newRotatingCredentials(cfg.AccessKeyID, cfg.SecretAccessKey)
+	credsCache := aws.NewCredentialsCache(creds)
 	awsSDKConfig, err = awsconfig.LoadDefaultConfig(context.TODO(),
 		awsconfig.WithRegion(cfg.Region),
-		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, "")),
+		awsconfig.WithCredentialsProvider(credsCache),
 		awsconfig.WithEndpointResolverWithOptions(customResolver),
 	)
 	if err != nil {
@@ -57,6 +68,7 @@ func NewS3Service(cfg config.S3Config, appLogger *logger.Logger) (*S3Service, er
 	// For MinIO, UsePathStyle should be true.
 	s3Client := s3.NewFromConfig(awsSDKConfig, func(o *s3.Options) {
 		o.UsePathStyle = cfg.UsePathStyle // Important for MinIO
+		o.APIOptions = append(o.APIOptions, tracingMiddleware(cfg.BucketName))
 	})
 
 	appLogger.Info("S3 Service initialized",
@@ -67,17 +79,33 @@ func NewS3Service(cfg config.S3Config, appLogger *logger.Logger) (*S3Service, er
 
 	return &S3Service{
 		client:     s3Client,
+		creds:      creds,
+		credsCache: credsCache,
 		bucketName: cfg.BucketName,
 		logger:     appLogger,
 		endpoint:   cfg.Endpoint,
 	}, nil
 }
 
-// UploadFile uploads a file to the S3 bucket.
+// SetCredentials replaces the access keys used for subsequent requests.
+// An empty argument keeps the current value, so the two keys can rotate separately.
+func (s *S3Service) SetCredentials(accessKeyID, secretAccessKey string) {
+	current := s.creds.creds.Load()
+	if accessKeyID == "" {
+		accessKeyID = current.AccessKeyID
+	}
+	if secretAccessKey == "" {
+		secretAccessKey = current.SecretAccessKey
+	}
+	s.creds.set(accessKeyID, secretAccessKey)
+	s.credsCache.Invalidate()
+}
+
+// Put uploads a file to the S3 bucket.
 // s3Key is the full path/name of the object in the bucket.
 // file is an io.Reader for the file content.
 // contentType is the MIME type of the file (e.g., "audio/mpeg").
-func (s *S3Service) UploadFile(ctx context.Context, s3Key string, file io.Reader, contentType string) (string, error) {
+func (s *S3Service) Put(ctx context.Context, s3Key string, file io.Reader, contentType string) (string, error) {
 	params := &s3.PutObjectInput{
 		Bucket:      aws.String(s.bucketName),
 		Key:         aws.String(s3Key),
@@ -88,7 +116,7 @@ func (s *S3Service) UploadFile(ctx context.Context, s3Key string, file io.Reader
 
 	_, err := s.client.PutObject(ctx, params)
 	if err != nil {
-		s.logger.Error("Failed to upload file to S3",
+		s.logger.FromContext(ctx).Error("Failed to upload file to S3",
 			zap.String("bucket", s.bucketName),
 			zap.String("key", s3Key),
 			zap.Error(err))
@@ -98,35 +126,133 @@ func (s *S3Service) UploadFile(ctx context.Context, s3Key string, file io.Reader
 	// Construct the URL. This can be complex depending on public/private, CDN, etc.
 	// For a simple MinIO setup, it might be: endpoint/bucketName/s3Key
 	fileURL := fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(s.endpoint, "/"), s.bucketName, s3Key)
-	s.logger.Info("File uploaded successfully to S3", zap.String("key", s3Key), zap.String("url", fileURL))
+	s.logger.FromContext(ctx).Info("File uploaded successfully to S3", zap.String("key", s3Key), zap.String("url", fileURL))
 
 	return fileURL, nil
 }
 
-// DeleteFile (Optional) - implement if needed
-// func (s *S3Service) DeleteFile(ctx context.Context, s3Key string) error {
-// 	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
-// 		Bucket: aws.String(s.bucketName),
-// 		Key:    aws.String(s3Key),
-// 	})
-// 	if err != nil {
-// 		return fmt.Errorf("failed to delete file from S3: %w", err)
-// 	}
-// 	return nil
-// }
-
-// GetPresignedURL (Optional) - for generating temporary access URLs to private objects
-// func (s *S3Service) GetPresignedURL(ctx context.Context, s3Key string, lifetimeSecs int64) (string, error) {
-// 	presignClient := s3.NewPresignClient(s.client)
-// 	presignedURL, err := presignClient.PresignPutObject(ctx,
-// 		&s3.PutObjectInput{
-// 			Bucket: aws.String(s.bucketName),
-// 			Key:    aws.String(s3Key),
-// 		},
-// 		s3.WithPresignExpires(time.Duration(lifetimeSecs*int64(time.Second))),
-// 	)
-// 	if err != nil {
-// 		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
-// 	}
-// 	return presignedURL.URL, nil
-// }
+// Get downloads an object, or the byte range selected by rng.
+func (s *S3Service) Get(ctx context.Context, s3Key string, rng *storage.ByteRange) (io.ReadCloser, *storage.ObjectInfo, error) {
+	params := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(s3Key),
+	}
+	if rng != nil {
+		if rng.End < 0 {
+			params.Range = aws.String(fmt.Sprintf("bytes=%d-", rng.Start))
+		} else {
+			params.Range = aws.String(fmt.Sprintf("bytes=%d-%d", rng.Start, rng.End))
+		}
+	}
+
+	out, err := s.client.GetObject(ctx, params)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil, storage.ErrNotFound
+		}
+		s.logger.FromContext(ctx).Error("Failed to get file from S3", zap.String("bucket", s.bucketName), zap.String("key", s3Key), zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to get file from S3 bucket %s with key %s: %w", s.bucketName, s3Key, err)
+	}
+	return out.Body, &storage.ObjectInfo{
+		Key:          s3Key,
+		SizeBytes:    aws.ToInt64(out.ContentLength),
+		ContentType:  aws.ToString(out.ContentType),
+		LastModified: aws.ToTime(out.LastModified),
+	}, nil
+}
+
+// Head returns object metadata without downloading the body.
+func (s *S3Service) Head(ctx context.Context, s3Key string) (*storage.ObjectInfo, error) {
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(s3Key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, storage.ErrNotFound
+		}
+		s.logger.FromContext(ctx).Error("Failed to head file in S3", zap.String("bucket", s.bucketName), zap.String("key", s3Key), zap.Error(err))
+		return nil, fmt.Errorf("failed to head file in S3 bucket %s with key %s: %w", s.bucketName, s3Key, err)
+	}
+	return &storage.ObjectInfo{
+		Key:          s3Key,
+		SizeBytes:    aws.ToInt64(out.ContentLength),
+		ContentType:  aws.ToString(out.ContentType),
+		LastModified: aws.ToTime(out.LastModified),
+	}, nil
+}
+
+// Delete removes an object from the S3 bucket.
+// Deleting a key that does not exist is not an error.
+func (s *S3Service) Delete(ctx context.Context, s3Key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(s3Key),
+	})
+	if err != nil {
+		s.logger.FromContext(ctx).Error("Failed to delete file from S3", zap.String("bucket", s.bucketName), zap.String("key", s3Key), zap.Error(err))
+		return fmt.Errorf("failed to delete file from S3 bucket %s with key %s: %w", s.bucketName, s3Key, err)
+	}
+	s.logger.FromContext(ctx).Info("File deleted from S3", zap.String("key", s3Key))
+	return nil
+}
+
+// List pages through every object under prefix using ListObjectsV2
+// and calls fn for each one, in lexicographic key order.
+// Iteration stops at the first error returned by fn.
+func (s *S3Service) List(ctx context.Context, prefix string, fn func(storage.ObjectInfo) error) error {
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucketName),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			s.logger.FromContext(ctx).Error("Failed to list objects in S3", zap.String("bucket", s.bucketName), zap.String("prefix", prefix), zap.Error(err))
+			return fmt.Errorf("failed to list objects in S3 bucket %s: %w", s.bucketName, err)
+		}
+		for _, obj := range page.Contents {
+			info := storage.ObjectInfo{
+				Key:          aws.ToString(obj.Key),
+				SizeBytes:    aws.ToInt64(obj.Size),
+				LastModified: aws.ToTime(obj.LastModified),
+			}
+			if err := fn(info); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Presign generates a temporary download URL for a private object.
+func (s *S3Service) Presign(ctx context.Context, s3Key string, expires time.Duration) (string, error) {
+	presignClient := s3.NewPresignClient(s.client)
+	req, err := presignClient.PresignGetObject(ctx,
+		&s3.GetObjectInput{
+			Bucket: aws.String(s.bucketName),
+			Key:    aws.String(s3Key),
+		},
+		s3.WithPresignExpires(expires),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned URL for key %s: %w", s3Key, err)
+	}
+	return req.URL, nil
+}
+
+// Ping checks that the bucket exists and is accessible with HeadBucket.
+func (s *S3Service) Ping(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.bucketName)})
+	if err != nil {
+		return fmt.Errorf("failed to reach S3 bucket %s: %w", s.bucketName, err)
+	}
+	return nil
+}
+
+// isNotFound reports whether err is S3's "no such key" for GetObject or HeadObject.
+func isNotFound(err error) bool {
+	var noSuchKey *types.NoSuchKey
+	var notFound *types.NotFound
+	return errors.As(err, &noSuchKey) || errors.As(err, &notFound)
+}
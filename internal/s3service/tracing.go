@@ -0,0 +1,37 @@
+package s3service
+
+import (
+	"context"
+
+	"example.com/auth_service/internal/tracing"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingMiddleware returns an S3 client middleware that wraps every API call,
+// including its retries, in a client span named after the operation.
+func tracingMiddleware(bucket string) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("OTelSpan",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				operation := awsmiddleware.GetOperationName(ctx)
+				ctx, span := tracing.Tracer().Start(ctx, "S3 "+operation,
+					trace.WithSpanKind(trace.SpanKindClient),
+					trace.WithAttributes(
+						attribute.String("rpc.system", "aws-api"),
+						attribute.String("rpc.service", "S3"),
+						attribute.String("rpc.method", operation),
+						attribute.String("aws.s3.bucket", bucket),
+					))
+				out, md, err := next.HandleInitialize(ctx, in)
+				if isNotFound(err) {
+					tracing.End(span, nil) // A missing key is an answer, not a failure
+				} else {
+					tracing.End(span, err)
+				}
+				return out, md, err
+			}), middleware.After)
+	}
+}
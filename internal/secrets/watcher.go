@@ -0,0 +1,85 @@
+// Package secrets reloads secrets mounted as files (e.g. Kubernetes secret
+// volumes) when they are rotated, without restarting the process.
+package secrets
+
+import (
+	"context"
+	"time"
+
+	"example.com/auth_service/internal/config"
+	"example.com/auth_service/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// watchedFile is one secret file and the value last applied from it.
+type watchedFile struct {
+	name     string
+	path     string
+	current  string
+	onChange func(value string) error
+}
+
+// Watcher polls secret files and calls a callback whenever one changes.
+// Polling, rather than inotify, also works with the symlink swaps Kubernetes
+// uses to update secret volumes.
+type Watcher struct {
+	interval time.Duration
+	files    []*watchedFile
+	logger   *logger.Logger
+}
+
+// NewWatcher creates a Watcher that checks its files every interval.
+func NewWatcher(interval time.Duration, appLogger *logger.Logger) *Watcher {
+	return &Watcher{interval: interval, logger: appLogger}
+}
+
+// Watch registers a secret file. current is the value already in use;
+// onChange is called with each new value. If onChange fails, the rotation
+// is logged and retried on the next poll.
+func (w *Watcher) Watch(name, path, current string, onChange func(value string) error) {
+	w.files = append(w.files, &watchedFile{name: name, path: path, current: current, onChange: onChange})
+}
+
+// Run polls until ctx is cancelled. It returns at once if nothing is watched
+// or the interval is zero.
+func (w *Watcher) Run(ctx context.Context) {
+	if len(w.files) == 0 || w.interval <= 0 {
+		return
+	}
+	w.logger.Info("Watching secret files for rotation", zap.Int("files", len(w.files)), zap.Duration("interval", w.interval))
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check re-reads every file and applies the ones that changed.
+func (w *Watcher) check() {
+	for _, f := range w.files {
+		value, err := config.ReadSecretFile(f.path)
+		if err != nil {
+			// Mid-rotation the file can briefly be missing; keep the current value.
+			w.logger.Warn("Failed to read secret file", zap.String("secret", f.name), zap.String("path", f.path), zap.Error(err))
+			continue
+		}
+		if value == f.current {
+			continue
+		}
+		if value == "" {
+			w.logger.Warn("Ignoring empty secret file", zap.String("secret", f.name), zap.String("path", f.path))
+			continue
+		}
+		if err := f.onChange(value); err != nil {
+			w.logger.Error("Failed to apply rotated secret", zap.String("secret", f.name), zap.String("path", f.path), zap.Error(err))
+			continue
+		}
+		f.current = value
+		w.logger.Info("Secret rotated", zap.String("secret", f.name), zap.String("path", f.path))
+	}
+}
@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"example.com/auth_service/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	localObjectsDir = "objects" // Object bodies, laid out by key
+	localMetaDir    = "meta"    // Content type of each object, same layout
+)
+
+// LocalStore is a BlobStore backed by a directory on the local filesystem.
+// It lets the service run without MinIO during development.
+type LocalStore struct {
+	root   string
+	logger *logger.Logger
+}
+
+// NewLocalStore creates a LocalStore rooted at dir, creating it if needed.
+func NewLocalStore(dir string, appLogger *logger.Logger) (*LocalStore, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("local store: invalid root %s: %w", dir, err)
+	}
+	for _, sub := range []string{localObjectsDir, localMetaDir} {
+		if err := os.MkdirAll(filepath.Join(root, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("local store: failed to create %s: %w", sub, err)
+		}
+	}
+	appLogger.Info("Local blob store initialized", zap.String("root", root))
+	return &LocalStore{root: root, logger: appLogger}, nil
+}
+
+// Put writes body to a temporary file and renames it into place.
+func (l *LocalStore) Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error) {
+	objPath, metaPath, err := l.paths(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(objPath), 0o755); err != nil {
+		return "", fmt.Errorf("local store: failed to create directory for %s: %w", key, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0o755); err != nil {
+		return "", fmt.Errorf("local store: failed to create directory for %s: %w", key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(objPath), ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("local store: failed to create temp file for %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("local store: failed to write %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("local store: failed to write %s: %w", key, err)
+	}
+	if err := os.WriteFile(metaPath, []byte(contentType), 0o644); err != nil {
+		return "", fmt.Errorf("local store: failed to write metadata for %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), objPath); err != nil {
+		return "", fmt.Errorf("local store: failed to move %s into place: %w", key, err)
+	}
+
+	l.logger.Info("File stored locally", zap.String("key", key), zap.String("path", objPath))
+	return "file://" + filepath.ToSlash(objPath), nil
+}
+
+// Get opens the object, seeking to the start of rng when one is given.
+func (l *LocalStore) Get(ctx context.Context, key string, rng *ByteRange) (io.ReadCloser, *ObjectInfo, error) {
+	info, err := l.Head(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	objPath, _, _ := l.paths(key) // Validated by Head
+	f, err := os.Open(objPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("local store: failed to open %s: %w", key, err)
+	}
+	if rng == nil {
+		return f, info, nil
+	}
+
+	start, end, err := clampRange(rng, info.SizeBytes)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("local store: failed to seek %s: %w", key, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, end-start+1), f}, info, nil
+}
+
+// Head returns object metadata.
+func (l *LocalStore) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	objPath, metaPath, err := l.paths(key)
+	if err != nil {
+		return nil, err
+	}
+	st, err := os.Stat(objPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("local store: failed to stat %s: %w", key, err)
+	}
+	if st.IsDir() {
+		return nil, ErrNotFound
+	}
+	contentType := "application/octet-stream"
+	if meta, err := os.ReadFile(metaPath); err == nil && len(meta) > 0 {
+		contentType = string(meta)
+	}
+	return &ObjectInfo{Key: key, SizeBytes: st.Size(), ContentType: contentType, LastModified: st.ModTime()}, nil
+}
+
+// Delete removes the object and its metadata.
+func (l *LocalStore) Delete(ctx context.Context, key string) error {
+	objPath, metaPath, err := l.paths(key)
+	if err != nil {
+		return err
+	}
+	for _, p := range []string{objPath, metaPath} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("local store: failed to delete %s: %w", key, err)
+		}
+	}
+	l.logger.Info("File deleted locally", zap.String("key", key))
+	return nil
+}
+
+// List walks the object tree and calls fn in byte-wise key order.
+// Directory walk order differs from key order ("a/b" vs "a-b"), so keys are sorted first.
+func (l *LocalStore) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	base := filepath.Join(l.root, localObjectsDir)
+	var keys []string
+	err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("local store: failed to list objects: %w", err)
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		info, err := l.Head(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			continue // Deleted while listing
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(*info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Presign returns a file:// URL. It is not signed and carries no expiry.
+func (l *LocalStore) Presign(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if _, err := l.Head(ctx, key); err != nil {
+		return "", err
+	}
+	objPath, _, _ := l.paths(key)
+	return "file://" + filepath.ToSlash(objPath), nil
+}
+
+// Ping checks that the objects directory still exists.
+func (l *LocalStore) Ping(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(l.root, localObjectsDir)); err != nil {
+		return fmt.Errorf("local store: %w", err)
+	}
+	return nil
+}
+
+// paths maps a key to its object and metadata file, rejecting keys that
+// would escape the store root.
+func (l *LocalStore) paths(key string) (string, string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("local store: invalid key %q", key)
+	}
+	return filepath.Join(l.root, localObjectsDir, clean), filepath.Join(l.root, localMetaDir, clean), nil
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	data []byte
+	info ObjectInfo
+}
+
+// MemoryStore is a BlobStore that keeps objects in memory.
+// It is intended for tests and throwaway local runs; nothing survives a restart.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{objects: make(map[string]memoryObject)}
+}
+
+// Put stores body under key.
+func (m *MemoryStore) Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", fmt.Errorf("memory store: failed to read body for %s: %w", key, err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = memoryObject{
+		data: data,
+		info: ObjectInfo{Key: key, SizeBytes: int64(len(data)), ContentType: contentType, LastModified: time.Now()},
+	}
+	return "memory://" + key, nil
+}
+
+// Get returns the object, or the requested range of it.
+func (m *MemoryStore) Get(ctx context.Context, key string, rng *ByteRange) (io.ReadCloser, *ObjectInfo, error) {
+	m.mu.RLock()
+	obj, ok := m.objects[key]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	info := obj.info
+	data := obj.data
+	if rng != nil {
+		start, end, err := clampRange(rng, int64(len(data)))
+		if err != nil {
+			return nil, nil, err
+		}
+		data = data[start : end+1]
+	}
+	return io.NopCloser(bytes.NewReader(data)), &info, nil
+}
+
+// Head returns object metadata.
+func (m *MemoryStore) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	m.mu.RLock()
+	obj, ok := m.objects[key]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	info := obj.info
+	return &info, nil
+}
+
+// Delete removes the object.
+func (m *MemoryStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	delete(m.objects, key)
+	m.mu.Unlock()
+	return nil
+}
+
+// List calls fn for every object under prefix in key order.
+func (m *MemoryStore) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	m.mu.RLock()
+	infos := make([]ObjectInfo, 0, len(m.objects))
+	for key, obj := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			infos = append(infos, obj.info)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	for _, info := range infos {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Presign returns a memory:// URL. It is not signed and carries no expiry.
+func (m *MemoryStore) Presign(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if _, err := m.Head(ctx, key); err != nil {
+		return "", err
+	}
+	return "memory://" + key, nil
+}
+
+// Ping always succeeds.
+func (m *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// clampRange validates rng against an object of the given size and
+// returns inclusive start and end offsets.
+func clampRange(rng *ByteRange, size int64) (int64, int64, error) {
+	end := rng.End
+	if end < 0 || end >= size {
+		end = size - 1
+	}
+	if rng.Start < 0 || rng.Start > end {
+		return 0, 0, fmt.Errorf("storage: invalid range %d-%d for object of %d bytes", rng.Start, rng.End, size)
+	}
+	return rng.Start, end, nil
+}
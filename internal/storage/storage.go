@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when the requested object does not exist.
+var ErrNotFound = errors.New("storage: object not found")
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Key          string
+	SizeBytes    int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// ByteRange selects part of an object for Get.
+// Both offsets are inclusive; a negative End reads to the end of the object.
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+// BlobStore is the object storage used for uploaded audio.
+// S3Service is the production implementation; LocalStore and MemoryStore
+// exist for local development and hermetic tests.
+type BlobStore interface {
+	// Put stores body under key and returns a URL for the object.
+	Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error)
+	// Get opens the object, or the part selected by rng when it is non-nil.
+	// The caller must close the returned reader.
+	Get(ctx context.Context, key string, rng *ByteRange) (io.ReadCloser, *ObjectInfo, error)
+	// Head returns object metadata without reading the body.
+	Head(ctx context.Context, key string) (*ObjectInfo, error)
+	// Delete removes the object. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// List calls fn for every object under prefix in byte-wise key order,
+	// stopping at the first error returned by fn.
+	List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+	// Presign returns a time-limited URL for downloading the object.
+	Presign(ctx context.Context, key string, expires time.Duration) (string, error)
+	// Ping checks that the store is reachable and usable.
+	Ping(ctx context.Context) error
+}
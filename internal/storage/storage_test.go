@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"example.com/auth_service/pkg/logger"
+)
+
+// stores returns a fresh instance of each BlobStore implementation that runs without external services.
+func stores(t *testing.T) map[string]BlobStore {
+	t.Helper()
+	appLogger, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatalf("logger: %v", err)
+	}
+	local, err := NewLocalStore(t.TempDir(), appLogger)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	return map[string]BlobStore{
+		"local":  local,
+		"memory": NewMemoryStore(),
+	}
+}
+
+func put(t *testing.T, store BlobStore, key, body, contentType string) {
+	t.Helper()
+	if _, err := store.Put(context.Background(), key, strings.NewReader(body), contentType); err != nil {
+		t.Fatalf("Put(%q): %v", key, err)
+	}
+}
+
+func read(t *testing.T, store BlobStore, key string, rng *ByteRange) (string, *ObjectInfo) {
+	t.Helper()
+	body, info, err := store.Get(context.Background(), key, rng)
+	if err != nil {
+		t.Fatalf("Get(%q, %v): %v", key, rng, err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("read %q: %v", key, err)
+	}
+	return string(data), info
+}
+
+func TestPutGetHeadDelete(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			put(t, store, "user/1/a.wav", "RIFF audio", "audio/wav")
+
+			data, info := read(t, store, "user/1/a.wav", nil)
+			if data != "RIFF audio" {
+				t.Errorf("Get body = %q, want %q", data, "RIFF audio")
+			}
+			if info.SizeBytes != 10 || info.ContentType != "audio/wav" || info.Key != "user/1/a.wav" {
+				t.Errorf("Get info = %+v", info)
+			}
+
+			head, err := store.Head(ctx, "user/1/a.wav")
+			if err != nil {
+				t.Fatalf("Head: %v", err)
+			}
+			if head.SizeBytes != 10 || head.ContentType != "audio/wav" {
+				t.Errorf("Head = %+v", head)
+			}
+
+			put(t, store, "user/1/a.wav", "replaced", "audio/mpeg")
+			if data, info := read(t, store, "user/1/a.wav", nil); data != "replaced" || info.ContentType != "audio/mpeg" {
+				t.Errorf("after overwrite: body %q, content type %q", data, info.ContentType)
+			}
+
+			if err := store.Delete(ctx, "user/1/a.wav"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if _, err := store.Head(ctx, "user/1/a.wav"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Head after Delete: err = %v, want ErrNotFound", err)
+			}
+			if _, _, err := store.Get(ctx, "user/1/a.wav", nil); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+			}
+			if err := store.Delete(ctx, "user/1/a.wav"); err != nil {
+				t.Errorf("Delete of a missing key: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		rng     ByteRange
+		want    string
+		wantErr bool
+	}{
+		{name: "middle", rng: ByteRange{Start: 2, End: 4}, want: "234"},
+		{name: "first byte", rng: ByteRange{Start: 0, End: 0}, want: "0"},
+		{name: "open end", rng: ByteRange{Start: 7, End: -1}, want: "789"},
+		{name: "end past size", rng: ByteRange{Start: 8, End: 100}, want: "89"},
+		{name: "start past end", rng: ByteRange{Start: 5, End: 3}, wantErr: true},
+		{name: "start past size", rng: ByteRange{Start: 10, End: -1}, wantErr: true},
+		{name: "negative start", rng: ByteRange{Start: -1, End: 3}, wantErr: true},
+	}
+	for name, store := range stores(t) {
+		put(t, store, "digits", "0123456789", "text/plain")
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				rng := tt.rng
+				if tt.wantErr {
+					if _, _, err := store.Get(context.Background(), "digits", &rng); err == nil {
+						t.Errorf("Get(%+v) succeeded, want error", rng)
+					}
+					return
+				}
+				data, info := read(t, store, "digits", &rng)
+				if data != tt.want {
+					t.Errorf("Get(%+v) = %q, want %q", rng, data, tt.want)
+				}
+				if info.SizeBytes != 10 {
+					t.Errorf("Get(%+v) info size = %d, want the whole object's 10", rng, info.SizeBytes)
+				}
+			})
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	// "a-b" sorts before "a/b" byte-wise, unlike in a directory walk.
+	keys := []string{"b/2", "a/b/1", "a-b", "a/a", "c", "a/b/0"}
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "", want: []string{"a-b", "a/a", "a/b/0", "a/b/1", "b/2", "c"}},
+		{prefix: "a/", want: []string{"a/a", "a/b/0", "a/b/1"}},
+		{prefix: "a/b/", want: []string{"a/b/0", "a/b/1"}},
+		{prefix: "a", want: []string{"a-b", "a/a", "a/b/0", "a/b/1"}},
+		{prefix: "d", want: nil},
+	}
+	for name, store := range stores(t) {
+		for _, key := range keys {
+			put(t, store, key, key, "text/plain")
+		}
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.prefix, func(t *testing.T) {
+				var got []string
+				err := store.List(context.Background(), tt.prefix, func(info ObjectInfo) error {
+					if info.SizeBytes != int64(len(info.Key)) {
+						t.Errorf("%s: size %d, want %d", info.Key, info.SizeBytes, len(info.Key))
+					}
+					got = append(got, info.Key)
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("List: %v", err)
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("List(%q) = %q, want %q", tt.prefix, got, tt.want)
+				}
+			})
+		}
+
+		t.Run(name+"/stops on error", func(t *testing.T) {
+			stop := errors.New("stop")
+			calls := 0
+			err := store.List(context.Background(), "", func(ObjectInfo) error {
+				calls++
+				return stop
+			})
+			if !errors.Is(err, stop) || calls != 1 {
+				t.Errorf("List returned %v after %d calls, want the callback's error after 1", err, calls)
+			}
+		})
+	}
+}
+
+func TestLocalStorePaths(t *testing.T) {
+	appLogger, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatalf("logger: %v", err)
+	}
+	store, err := NewLocalStore(t.TempDir(), appLogger)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	objects := filepath.Join(store.root, localObjectsDir)
+
+	tests := []struct {
+		key     string
+		want    string // Relative to the objects directory
+		wantErr bool
+	}{
+		{key: "a/b.wav", want: filepath.Join("a", "b.wav")},
+		{key: "a/./b.wav", want: filepath.Join("a", "b.wav")},
+		{key: "a/../b.wav", want: "b.wav"},
+		{key: "..a/b", want: filepath.Join("..a", "b")},
+		{key: "", wantErr: true},
+		{key: ".", wantErr: true},
+		{key: "..", wantErr: true},
+		{key: "../b.wav", wantErr: true},
+		{key: "a/../../b.wav", wantErr: true},
+		{key: "/etc/passwd", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			objPath, _, err := store.paths(tt.key)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("paths(%q) = %q, want error", tt.key, objPath)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("paths(%q): %v", tt.key, err)
+			}
+			if want := filepath.Join(objects, tt.want); objPath != want {
+				t.Errorf("paths(%q) = %q, want %q", tt.key, objPath, want)
+			}
+		})
+	}
+
+	// Every method goes through paths, so an escaping key never touches the filesystem.
+	if _, err := store.Put(context.Background(), "../escape", strings.NewReader("x"), "text/plain"); err == nil {
+		t.Error("Put with an escaping key succeeded")
+	}
+	if _, err := store.Head(context.Background(), "../../etc/passwd"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Head with an escaping key: err = %v, want an invalid key error", err)
+	}
+}
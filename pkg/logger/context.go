@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// requestFieldsKey is the context key for the request-scoped log fields.
+type requestFieldsKey struct{}
+
+// requestFields are added to every log line written through FromContext.
+// It is shared by pointer so that middleware further down the chain (e.g.
+// authentication) can fill in fields that outer middleware then logs.
+type requestFields struct {
+	requestID string
+	userID    string
+	route     string
+}
+
+// NewRequestContext returns a copy of ctx carrying the request ID and route template.
+func NewRequestContext(ctx context.Context, requestID, route string) context.Context {
+	return context.WithValue(ctx, requestFieldsKey{}, &requestFields{requestID: requestID, route: route})
+}
+
+// SetUserID records the authenticated user for the request carried by ctx.
+// It does nothing if ctx has no request fields.
+func SetUserID(ctx context.Context, userID string) {
+	if f, ok := ctx.Value(requestFieldsKey{}).(*requestFields); ok {
+		f.userID = userID
+	}
+}
+
+// RequestID returns the request ID carried by ctx, or "" if there is none.
+func RequestID(ctx context.Context) string {
+	if f, ok := ctx.Value(requestFieldsKey{}).(*requestFields); ok {
+		return f.requestID
+	}
+	return ""
+}
+
+// FromContext returns a child logger with the request_id, user_id and route
+// carried by ctx. Outside a request it returns l unchanged.
+func (l *Logger) FromContext(ctx context.Context) *Logger {
+	f, ok := ctx.Value(requestFieldsKey{}).(*requestFields)
+	if !ok {
+		return l
+	}
+	fields := []interface{}{zap.String("request_id", f.requestID)}
+	if f.userID != "" {
+		fields = append(fields, zap.String("user_id", f.userID))
+	}
+	if f.route != "" {
+		fields = append(fields, zap.String("route", f.route))
+	}
+	return &Logger{SugaredLogger: l.SugaredLogger.With(fields...)}
+}
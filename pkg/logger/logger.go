@@ -45,8 +45,9 @@ func New(level string, format string) (*Logger, error) {
 		logLevel,
 	)
 
-	// Add caller and stacktrace for error levels and above
-	zapLogger := zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
+	// Add caller and stacktrace for error levels and above.
+	// Skip one frame so the caller is the code calling the Logger methods below.
+	zapLogger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1), zap.AddStacktrace(zapcore.ErrorLevel))
 	sugaredLogger := zapLogger.Sugar()
 
 	return &Logger{SugaredLogger: sugaredLogger}, nil
@@ -60,7 +61,31 @@ func (l *Logger) Sync() error {
 	return l.SugaredLogger.Sync()
 }
 
-// Example of adding structured context to logs
-// func (l *Logger) WithContext(fields ...interface{}) *Logger {
-// 	return &Logger{l.SugaredLogger.With(fields...)}
-// }
+// The level methods take a message followed by zap.Field values (or loosely
+// typed key-value pairs), so fields are logged as structured fields instead
+// of being formatted into the message.
+
+// Debug logs a message at debug level with structured fields.
+func (l *Logger) Debug(msg string, fields ...interface{}) {
+	l.SugaredLogger.Debugw(msg, fields...)
+}
+
+// Info logs a message at info level with structured fields.
+func (l *Logger) Info(msg string, fields ...interface{}) {
+	l.SugaredLogger.Infow(msg, fields...)
+}
+
+// Warn logs a message at warn level with structured fields.
+func (l *Logger) Warn(msg string, fields ...interface{}) {
+	l.SugaredLogger.Warnw(msg, fields...)
+}
+
+// Error logs a message at error level with structured fields.
+func (l *Logger) Error(msg string, fields ...interface{}) {
+	l.SugaredLogger.Errorw(msg, fields...)
+}
+
+// Fatal logs a message at fatal level with structured fields, then exits.
+func (l *Logger) Fatal(msg string, fields ...interface{}) {
+	l.SugaredLogger.Fatalw(msg, fields...)
+}